@@ -0,0 +1,124 @@
+package origin
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Finding describes a dangerous or ineffective pattern found by
+// [Audit].
+type Finding struct {
+	Index   int    // index of the pattern in the list, or -1
+	Pattern string // the pattern itself
+	Message string // human-readable description of the issue
+}
+
+// String returns the finding formatted for display.
+func (f Finding) String() string {
+	if f.Index < 0 {
+		return f.Message
+	}
+	return fmt.Sprintf("pattern #%d (%q): %s", f.Index, f.Pattern, f.Message)
+}
+
+// Audit inspects the patterns in p, as they would be used with the
+// given options, such as by [Middleware], and reports the ones that:
+//
+//   - match any hostname, while [AllowCredentials] is set;
+//   - have a wildcard scheme, which also matches plain HTTP and schemes
+//     that aren't meant for the web;
+//   - have wildcards spanning a public suffix, such as "https://*.co.uk",
+//     matching the domains of unrelated owners;
+//   - use plain HTTP in a list of HTTPS patterns, except for the ones
+//     on the loopback interface;
+//   - can never match, because they are invalid, use a scheme that
+//     browsers never send in the origin header, or only match origins
+//     denied by a negated pattern.
+//
+// Unlike [Patterns.Lint], which reports mistakes, Audit reports
+// configurations that are valid but weaken a CORS policy, such as
+// before deploying it. An empty list is returned if no issue was found.
+// Invalid options are reported as a finding with the index -1.
+func Audit(p Patterns, opts ...Option) []Finding {
+	c, err := newConfig(opts)
+	if err != nil {
+		return []Finding{{Index: -1, Message: "invalid options: " + err.Error()}}
+	}
+
+	// Patterns are compiled without credentials, so that the ones
+	// rejected only because of them can be reported as dangerous.
+	ac := *c
+	ac.credentials = false
+
+	var findings []Finding
+	report := func(i int, format string, args ...any) {
+		findings = append(findings, Finding{
+			Index:   i,
+			Pattern: p[i],
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	compiled := make([]*Pattern, len(p))
+	https := false
+	for i, item := range p {
+		if compiled[i], err = compile(item, &ac); err != nil {
+			report(i, "never matches: %v", err)
+			continue
+		}
+		for _, v := range compiled[i].variants() {
+			https = https || (!compiled[i].deny && v.scheme == "https")
+		}
+	}
+
+	for i, x := range compiled {
+		if x == nil || x.deny || x.re != nil || x.raw == Loopback {
+			continue
+		}
+
+		var anyHost, anyScheme, publicSuffix, insecure, webSocket bool
+		for _, v := range x.variants() {
+			anyHost = anyHost || v.host.any
+			anyScheme = anyScheme || v.scheme == c.wildcard
+			publicSuffix = publicSuffix || (!v.host.any && v.host.spansPublicSuffix(c))
+			insecure = insecure || (v.scheme == "http" && !v.host.isLoopback())
+			webSocket = webSocket || (!c.webSockets && (v.scheme == "ws" || v.scheme == "wss"))
+		}
+
+		if anyHost && c.credentials {
+			report(i, "matches any hostname, while trusted with credentials")
+		}
+		if anyScheme {
+			report(i, "wildcard scheme: also matches plain HTTP and non-web schemes")
+		}
+		if publicSuffix {
+			report(i, "wildcard spans a public suffix: matches the domains of any owner")
+		}
+		if insecure && https {
+			report(i, "plain HTTP in a list of HTTPS patterns")
+		}
+		if webSocket {
+			report(i, "never matches: browsers send origins with an HTTP scheme, even for WebSockets")
+		}
+		for j, y := range compiled {
+			if y != nil && y.deny && covers(y, x) {
+				report(i, "never matches: every origin it matches is denied by pattern #%d", j)
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// isLoopback returns true if h only matches hostnames of the loopback
+// interface.
+func (h *hostname) isLoopback() bool {
+	switch {
+	case h.addr.IsValid():
+		return h.addr.Unmap().IsLoopback()
+	case h.prefix.IsValid():
+		return h.prefix.Bits() >= 8 && netip.MustParsePrefix("127.0.0.0/8").Contains(h.prefix.Addr()) ||
+			h.prefix.Addr() == netip.IPv6Loopback() && h.prefix.Bits() == 128
+	}
+	return len(h.labels) > 0 && h.labels[len(h.labels)-1] == "localhost"
+}
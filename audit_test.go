@@ -0,0 +1,74 @@
+package origin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	p := Patterns{
+		"https://example.com",
+		"*",
+		"*://example.dev:*",
+		"https://*.co.uk",
+		"http://example.org",
+		"http://localhost:3000",
+		"http://127.0.0.1:8080",
+		"wss://example.com",
+		"https://legacy.example.net",
+		"!https://*.example.net",
+		"example.io",
+		"{http,https}://example.io",
+		Loopback,
+		"!http://*.example.com",
+	}
+
+	want := map[int][]string{
+		1:  {"wildcard scheme"},
+		2:  {"wildcard scheme"},
+		3:  {"wildcard spans a public suffix"},
+		4:  {"plain HTTP"},
+		7:  {"never matches: browsers"},
+		8:  {"never matches: every origin it matches is denied by pattern #9"},
+		10: {"never matches: invalid pattern"},
+		11: {"plain HTTP"},
+	}
+
+	check := func(findings []Finding, want map[int][]string) {
+		t.Helper()
+		got := make(map[int][]string)
+		for _, f := range findings {
+			if f.Pattern != p[f.Index] {
+				t.Errorf("Finding: %v - wrong pattern", f)
+			}
+			got[f.Index] = append(got[f.Index], f.Message)
+		}
+		for i := range p {
+			if len(got[i]) != len(want[i]) {
+				t.Errorf("Pattern #%d - Wanted: %q, Got: %q", i, want[i], got[i])
+				continue
+			}
+			for k, msg := range want[i] {
+				if !strings.HasPrefix(got[i][k], msg) {
+					t.Errorf("Pattern #%d - Wanted: %q, Got: %q", i, msg, got[i][k])
+				}
+			}
+		}
+	}
+
+	check(Audit(p), want)
+
+	want[1] = []string{"matches any hostname", "wildcard scheme"}
+	check(Audit(p, AllowCredentials()), want)
+
+	want[1] = []string{"wildcard scheme"}
+	delete(want, 7)
+	check(Audit(p, MatchWebSockets()), want)
+
+	if findings := Audit(Patterns{"https://example.com"}); len(findings) != 0 {
+		t.Errorf("Wanted no finding, Got: %v", findings)
+	}
+	if findings := Audit(p, WithWildcard(':')); len(findings) != 1 || findings[0].Index != -1 {
+		t.Errorf("Wanted a finding for invalid options, Got: %v", findings)
+	}
+}
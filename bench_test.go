@@ -0,0 +1,61 @@
+package origin
+
+import (
+	"net/url"
+	"testing"
+)
+
+var benchOrigins = []string{
+	"https://example.com",
+	"https://sub.example.com:8443",
+	"http://[::1]:3000",
+	"HTTPS://Bücher.Example",
+}
+
+func BenchmarkSplit(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, origin := range benchOrigins {
+			Split(origin)
+		}
+	}
+}
+
+func BenchmarkURLParse(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, origin := range benchOrigins {
+			u, _ := url.Parse(origin)
+			u.Hostname()
+			u.Port()
+		}
+	}
+}
+
+func BenchmarkMatch(b *testing.B) {
+	p := MustCompile("https://*.example.com:*")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, origin := range benchOrigins {
+			p.Matches(origin)
+		}
+	}
+}
+
+func BenchmarkMatchExact(b *testing.B) {
+	p := MustCompile("https://example.com")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, origin := range benchOrigins {
+			p.Matches(origin)
+		}
+	}
+}
+
+func BenchmarkMatchAll(b *testing.B) {
+	p := Patterns{"https://example.com", "https://*.example.com:*", "!https://admin.example.com", "http://[::1]:*"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p.MatchAll(benchOrigins)
+	}
+}
@@ -0,0 +1,110 @@
+package origin
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is a [Matcher] remembering the decisions made by the matcher it
+// wraps for the origins checked most recently, so that the origins
+// seen repeatedly are only parsed and matched once.
+//
+// When the wrapped matcher is a [Store] or a [Remote], the decisions
+// remembered are discarded whenever its patterns change. Otherwise, the
+// wrapped matcher must always make the same decision for a given
+// origin. Since decisions remembered are not made again, the hooks
+// registered on the wrapped matcher, such as with [OnAllow], are only
+// called once per origin.
+//
+// It is safe for concurrent use, provided that the wrapped matcher is.
+type Cache struct {
+	m Matcher
+	n int
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	order   *list.List // of *cacheEntry, most recently used first
+}
+
+// cacheKey identifies a decision made about an origin, for a given
+// version of the patterns of the wrapped matcher.
+type cacheKey struct {
+	origin  string
+	version uint64
+}
+
+// cacheEntry is a decision remembered by a [Cache].
+type cacheEntry struct {
+	key     cacheKey
+	pattern string
+	ok      bool
+	err     error
+}
+
+// versioned is implemented by matchers whose patterns may change, and
+// which report a different version every time they do.
+type versioned interface {
+	version() uint64
+}
+
+// NewCache returns a [Cache] wrapping m, and remembering the decisions
+// made for the last n distinct origins. It panics if n is not positive.
+func NewCache(m Matcher, n int) *Cache {
+	if n <= 0 {
+		panic("origin: non-positive size for NewCache")
+	}
+	return &Cache{
+		m:       m,
+		n:       n,
+		entries: make(map[cacheKey]*list.Element, n),
+		order:   list.New(),
+	}
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (c *Cache) MatchOrigin(origin string) (bool, error) {
+	_, ok, err := c.matchPattern(origin)
+	return ok, err
+}
+
+func (c *Cache) matchPattern(origin string) (string, bool, error) {
+	key := cacheKey{origin: origin}
+	if v, ok := c.m.(versioned); ok {
+		key.version = v.version()
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		e := elem.Value.(*cacheEntry)
+		c.mu.Unlock()
+		return e.pattern, e.ok, e.err
+	}
+	c.mu.Unlock()
+
+	e := &cacheEntry{key: key}
+	if pm, ok := c.m.(patternMatcher); ok {
+		e.pattern, e.ok, e.err = pm.matchPattern(origin)
+	} else {
+		e.ok, e.err = c.m.MatchOrigin(origin)
+	}
+
+	c.mu.Lock()
+	if _, ok := c.entries[key]; !ok {
+		c.entries[key] = c.order.PushFront(e)
+		if c.order.Len() > c.n {
+			oldest := c.order.Remove(c.order.Back()).(*cacheEntry)
+			delete(c.entries, oldest.key)
+		}
+	}
+	c.mu.Unlock()
+
+	return e.pattern, e.ok, e.err
+}
+
+// Len returns the number of decisions currently remembered.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,68 @@
+package origin
+
+import "testing"
+
+// countingMatcher is a Matcher counting the calls it receives.
+type countingMatcher struct {
+	p     Patterns
+	calls int
+}
+
+func (m *countingMatcher) MatchOrigin(origin string) (bool, error) {
+	m.calls++
+	return m.p.Match(origin)
+}
+
+func TestCache(t *testing.T) {
+	m := &countingMatcher{p: Patterns{"https://*.example.com"}}
+	c := NewCache(m, 2)
+
+	for _, origin := range []string{"https://a.example.com", "https://a.example.com", "https://b.example.com", "https://a.example.com"} {
+		if ok, err := c.MatchOrigin(origin); !ok || err != nil {
+			t.Errorf("Origin: %q - Got: %v, %v", origin, ok, err)
+		}
+	}
+	if m.calls != 2 {
+		t.Errorf("Wanted 2 calls, Got: %d", m.calls)
+	}
+
+	// Evicts "https://b.example.com", the least recently used.
+	if _, err := c.MatchOrigin("example.com"); err == nil {
+		t.Error("expected an error for an invalid origin")
+	}
+	c.MatchOrigin("https://b.example.com")
+	if m.calls != 4 || c.Len() != 2 {
+		t.Errorf("Wanted 4 calls and 2 entries, Got: %d, %d", m.calls, c.Len())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for a non-positive size")
+		}
+	}()
+	NewCache(m, 0)
+}
+
+func TestCacheStore(t *testing.T) {
+	s, err := NewStore(Patterns{"https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCache(s, 10)
+
+	if ok, _ := c.MatchOrigin("https://example.dev"); ok {
+		t.Error("Wanted no match")
+	}
+	if err := s.Add("https://example.dev"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := c.MatchOrigin("https://example.dev"); !ok {
+		t.Error("Wanted a match once the store changed")
+	}
+
+	r := NewRecorder(c, 1)
+	r.MatchOrigin("https://example.dev")
+	if got := r.Records()[0].Pattern; got != "https://example.dev" {
+		t.Errorf("Wanted pattern: %q, Got: %q", "https://example.dev", got)
+	}
+}
@@ -0,0 +1,63 @@
+package origin
+
+import "errors"
+
+// Reasons why an origin or a pattern is invalid, which can be tested
+// with [errors.Is].
+var (
+	ErrEmpty            = errors.New("empty string")
+	ErrMissingScheme    = errors.New("missing scheme")
+	ErrIllegalScheme    = errors.New("illegal scheme")
+	ErrMissingPort      = errors.New("missing port")
+	ErrIllegalPort      = errors.New("illegal port")
+	ErrMissingHostname  = errors.New("missing hostname")
+	ErrIllegalHostname  = errors.New("illegal hostname")
+	ErrNotCanonical     = errors.New("origin not serialized as by browsers")
+	ErrMultipleOrigins  = errors.New("multiple origin headers")
+	ErrTooManyWildcards = errors.New("too many wildcards")
+	ErrStrayWildcard    = errors.New("misplaced wildcard")
+	ErrInvalidEscape    = errors.New("invalid escape sequence")
+	ErrTooManyLabels    = errors.New("too many labels in hostname")
+	ErrWildcardScheme   = errors.New("wildcard scheme not allowed with credentials")
+	ErrDoubleNegation   = errors.New("double negation")
+	ErrPublicSuffix     = errors.New("hostname spans a public suffix")
+	ErrGlobalWildcard   = errors.New("hostname wildcard spans a top-level domain")
+	ErrUnanchored       = errors.New("hostname matches a varying number of labels")
+	ErrOpaqueOrigin     = errors.New("opaque origin not allowed")
+)
+
+// ErrInvalidOrigin is the error returned when an origin is malformed.
+// Since origins are sent by clients, such an error usually calls for
+// rejecting the request, rather than for a fix on the server-side.
+type ErrInvalidOrigin struct {
+	Origin string // the invalid origin
+	Reason error  // why it is invalid, such as ErrMissingScheme
+}
+
+func (e *ErrInvalidOrigin) Error() string {
+	return "invalid origin: " + e.Reason.Error()
+}
+
+// Unwrap returns the reason why the origin is invalid.
+func (e *ErrInvalidOrigin) Unwrap() error {
+	return e.Reason
+}
+
+// ErrInvalidPattern is the error returned when a pattern is malformed,
+// or not allowed by the options in use. Since patterns are usually
+// part of the configuration of a server, such an error usually calls
+// for a fix by its operator.
+type ErrInvalidPattern struct {
+	Pattern string // the invalid pattern
+	Pos     int    // byte offset of the invalid part of Pattern, or -1
+	Reason  error  // why it is invalid, such as ErrMissingScheme
+}
+
+func (e *ErrInvalidPattern) Error() string {
+	return "invalid pattern: " + e.Reason.Error()
+}
+
+// Unwrap returns the reason why the pattern is invalid.
+func (e *ErrInvalidPattern) Unwrap() error {
+	return e.Reason
+}
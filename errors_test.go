@@ -0,0 +1,109 @@
+package origin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrInvalidPattern(t *testing.T) {
+	type testCase struct {
+		Pattern string
+		Reason  error
+		Pos     int
+	}
+
+	var cases = []*testCase{
+		{"", ErrEmpty, 0},
+		{"example.com", ErrMissingScheme, 0},
+		{"1http://example.com", ErrIllegalScheme, 0},
+		{"https://example.com:0", ErrIllegalPort, 20},
+		{"https://example.com:443,9-8", ErrIllegalPort, 20},
+		{"foo://example.com", ErrMissingPort, 17},
+		{"!!https://example.com", ErrDoubleNegation, 1},
+		{"!example.com", ErrMissingScheme, 1},
+		{"ht*p://example.com", ErrStrayWildcard, 2},
+		{"https://example.com:8*", ErrStrayWildcard, 21},
+		{`https://a\b.example.com`, ErrInvalidEscape, 9},
+		{"https://a.**.example.com", ErrStrayWildcard, 8},
+		{`https://example.com\`, ErrInvalidEscape, 19},
+		{"https://", ErrMissingHostname, 8},
+		{"https://:8443", ErrMissingHostname, 8},
+		{"https://user@example.com", ErrIllegalHostname, 12},
+		{"https://example.com?x", ErrIllegalHostname, 19},
+		{"https://exa mple.com", ErrIllegalHostname, 11},
+		{"https://%65xample.com", ErrIllegalHostname, 8},
+	}
+
+	for _, tc := range cases {
+		_, err := Compile(tc.Pattern)
+
+		var perr *ErrInvalidPattern
+		if !errors.As(err, &perr) {
+			t.Errorf("Pattern: %q - Wanted an *ErrInvalidPattern, Got: %v", tc.Pattern, err)
+			continue
+		}
+		if !errors.Is(err, tc.Reason) {
+			t.Errorf("Pattern: %q - Wanted reason: %v, Got: %v", tc.Pattern, tc.Reason, perr.Reason)
+		}
+		if perr.Pattern != tc.Pattern || perr.Pos != tc.Pos {
+			t.Errorf("Pattern: %q - Wanted position: %d, Got: %q at %d", tc.Pattern, tc.Pos, perr.Pattern, perr.Pos)
+		}
+	}
+
+	if _, err := Compile("*://*.*.example.com", MaxWildcards(1)); !errors.Is(err, ErrTooManyWildcards) {
+		t.Errorf("Wanted: %v, Got: %v", ErrTooManyWildcards, err)
+	}
+	if _, err := Compile(`https://\*.*.example.com`, MaxWildcards(1)); err != nil {
+		t.Errorf("Escaped wildcard counted against the limit: %v", err)
+	}
+	if _, err := Compile("*", AllowCredentials()); !errors.Is(err, ErrWildcardScheme) {
+		t.Errorf("Wanted: %v, Got: %v", ErrWildcardScheme, err)
+	}
+
+	_, err := NewPatternSet(Patterns{"https://example.com", "example.com"})
+	if !errors.Is(err, ErrMissingScheme) {
+		t.Errorf("Wanted: %v, Got: %v", ErrMissingScheme, err)
+	}
+}
+
+func TestErrInvalidOrigin(t *testing.T) {
+	type testCase struct {
+		Origin string
+		Reason error
+	}
+
+	var cases = []*testCase{
+		{"example.com", ErrMissingScheme},
+		{"foo://example.com", ErrMissingPort},
+		{"https://", ErrMissingHostname},
+		{"://example.com", ErrMissingScheme},
+		{"https://user@example.com", ErrIllegalHostname},
+		{"https://example.com/", ErrIllegalHostname},
+		{"https://example.com?q=1", ErrIllegalHostname},
+		{"https://example.com#top", ErrIllegalHostname},
+		{"https://example.com:443/", ErrIllegalPort},
+		{"https://example.com:", ErrIllegalPort},
+		{"https://[::1", ErrIllegalHostname},
+		{"https://[127.0.0.1]", ErrIllegalHostname},
+		{"https://[::1]x", ErrIllegalHostname},
+		{"https://example.com:99999", ErrIllegalPort},
+	}
+
+	for _, tc := range cases {
+		_, err := Match(tc.Origin, "*")
+
+		var oerr *ErrInvalidOrigin
+		if !errors.As(err, &oerr) || oerr.Origin != tc.Origin {
+			t.Errorf("Origin: %q - Wanted an *ErrInvalidOrigin, Got: %v", tc.Origin, err)
+			continue
+		}
+		if !errors.Is(err, tc.Reason) {
+			t.Errorf("Origin: %q - Wanted reason: %v, Got: %v", tc.Origin, tc.Reason, oerr.Reason)
+		}
+	}
+
+	var perr *ErrInvalidPattern
+	if _, err := Match("example.com", "*"); errors.As(err, &perr) {
+		t.Errorf("Origin error reported as a pattern error: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package origin
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Components of an origin, as reported by [Explain].
+const (
+	ComponentScheme   = "scheme"
+	ComponentHostname = "hostname"
+	ComponentPort     = "port"
+)
+
+// Report describes the outcome of matching an origin against a
+// pattern, as returned by [Explain].
+type Report struct {
+	Origin  string
+	Pattern string
+	Match   bool
+
+	// Component is the first component of the origin that doesn't match
+	// the pattern, such as ComponentHostname, or an empty string if the
+	// origin matches, or if the pattern is a regular expression.
+	Component string
+
+	// Label is the index of the label of the hostname that doesn't match
+	// the pattern, from the left, or -1 if the mismatch isn't about a
+	// single label.
+	Label int
+
+	// Reason is a human-readable description of the outcome.
+	Reason string
+}
+
+// String returns the report formatted for display.
+func (r Report) String() string {
+	verdict := "no match"
+	if r.Match {
+		verdict = "match"
+	}
+	return fmt.Sprintf("%q, %q: %s: %s", r.Origin, r.Pattern, verdict, r.Reason)
+}
+
+// Explain matches origin against pattern, as [Match] does, and reports
+// why they match or not, such as which label of the hostname differs.
+// It is meant to help diagnosing unexpected decisions.
+//
+// An error is returned if either origin or pattern is malformed.
+func Explain(origin, pattern string, opts ...Option) (Report, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return Report{}, err
+	}
+
+	p, err := compile(pattern, c)
+	if err != nil {
+		return Report{}, err
+	}
+
+	r := Report{Origin: origin, Pattern: pattern, Label: -1}
+	if err := p.explain(&r); err != nil {
+		return Report{}, err
+	}
+
+	if p.deny {
+		r.Match = !r.Match
+		if r.Match {
+			r.Reason = "not denied, since " + r.Reason
+		} else {
+			r.Reason = "denied by negated pattern"
+		}
+	}
+	return r, nil
+}
+
+// explain fills in r, ignoring whether p is negated.
+func (p *Pattern) explain(r *Report) error {
+	if p.alts != nil {
+		// Explain the outcome for the scheme of the origin, if in the set.
+		scheme, _, _, _ := parseOrigin(r.Origin)
+		for _, alt := range p.alts[:len(p.alts)-1] {
+			if ok, _ := matchScheme(scheme, alt.scheme, p.c); ok {
+				return alt.explain(r)
+			}
+		}
+		return p.alts[len(p.alts)-1].explain(r)
+	}
+
+	ok, err := p.match(r.Origin)
+	if err != nil {
+		return err
+	}
+	r.Match = ok
+
+	switch {
+	case ok:
+		r.Reason = "all components match"
+		return nil
+	case r.Origin == opaque:
+		r.Reason = "opaque origin only matches pattern \"null\""
+		return nil
+	case p.opaque:
+		r.Reason = "pattern \"null\" only matches the opaque origin"
+		return nil
+	case p.re != nil:
+		r.Reason = "regular expression doesn't match"
+		return nil
+	}
+
+	os, oh, op, _, err := split(r.Origin, p.c)
+	if err != nil {
+		return err
+	}
+
+	if ok, _ := matchScheme(os, p.scheme, p.c); !ok {
+		r.Component = ComponentScheme
+		r.Reason = fmt.Sprintf("scheme %q doesn't match %q", strings.ToLower(os), p.scheme)
+		return nil
+	}
+
+	if ok, _ := matchHostname(oh, &p.host, p.c); !ok {
+		r.Component = ComponentHostname
+		r.Label, r.Reason = explainHostname(normalizeHost(oh), &p.host, p.c)
+		return nil
+	}
+
+	r.Component = ComponentPort
+	r.Reason = fmt.Sprintf("port %s doesn't match %q", op, p.port)
+	return nil
+}
+
+// explainHostname returns the index of the label of host that doesn't
+// match pattern, if any, and the reason why.
+func explainHostname(host string, pattern *hostname, c *config) (int, string) {
+	addr, err := netip.ParseAddr(host)
+	switch isIP := err == nil; {
+	case pattern.prefix.IsValid() && isIP:
+		return -1, fmt.Sprintf("IP address %s is not in network %s", addr, pattern.prefix)
+	case pattern.addr.IsValid() && isIP:
+		return -1, fmt.Sprintf("IP address %s is not %s", addr, pattern.addr)
+	case pattern.prefix.IsValid() || pattern.addr.IsValid():
+		return -1, fmt.Sprintf("hostname %q is not an IP address", host)
+	case isIP:
+		return -1, fmt.Sprintf("IP address %s is only matched by an IP address or network", addr)
+	}
+
+	labels := strings.Split(host, ".")
+	if len(labels) > c.maxLabels {
+		return -1, fmt.Sprintf("hostname %q has more than %d labels", host, c.maxLabels)
+	}
+
+	want := len(pattern.labels)
+	switch {
+	case pattern.suffix && len(labels) < want:
+		return -1, fmt.Sprintf("hostname %q has %d labels, pattern expects at least %d", host, len(labels), want)
+	case pattern.deep && len(labels) <= want:
+		return -1, fmt.Sprintf("hostname %q has %d labels, pattern expects more than %d", host, len(labels), want)
+	case !pattern.suffix && !pattern.deep && len(labels) != want:
+		return -1, fmt.Sprintf("hostname %q has %d labels, pattern expects %d", host, len(labels), want)
+	case c.maxDepth > 0 && len(labels)-want > c.maxDepth:
+		return -1, fmt.Sprintf("hostname %q is %d labels deep under the pattern, beyond the limit of %d", host, len(labels)-want, c.maxDepth)
+	}
+
+	offset := len(labels) - want
+	for i := want - 1; i >= 0; i-- {
+		a, b := pattern.labels[i], labels[offset+i]
+		if !matchLabel(b, a, c) {
+			return offset + i, fmt.Sprintf("label %q doesn't match %q", b, a)
+		}
+	}
+
+	domain, _ := publicsuffix.EffectiveTLDPlusOne(host)
+	return -1, fmt.Sprintf("hostname %q is not under registrable domain %q, but %q", host, pattern.registrable, domain)
+}
@@ -0,0 +1,61 @@
+package origin
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	type testCase struct {
+		Origin    string
+		Pattern   string
+		Match     bool
+		Component string
+		Label     int
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", "https://example.com", true, "", -1},
+		{"http://example.com", "https://example.com", false, ComponentScheme, -1},
+		{"https://staging.example.dev", "https://*.example.com", false, ComponentHostname, 2},
+		{"https://a.staging.example.com", "https://a.*.example.org", false, ComponentHostname, 3},
+		{"https://a.b.example.com", "https://*.example.com", false, ComponentHostname, -1},
+		{"https://example.com", "https://.sub.example.com", false, ComponentHostname, -1},
+		{"https://192.168.2.1", "https://192.168.1.0/24", false, ComponentHostname, -1},
+		{"https://example.com", "https://192.168.1.1", false, ComponentHostname, -1},
+		{"https://example.com:8443", "https://example.com", false, ComponentPort, -1},
+		{"https://legacy.example.com", "!https://legacy.example.com", false, "", -1},
+		{"https://example.com", "!https://legacy.example.com", true, ComponentHostname, -1},
+	}
+
+	for _, tc := range cases {
+		r, err := Explain(tc.Origin, tc.Pattern)
+		if err != nil {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+			continue
+		}
+		if r.Match != tc.Match || r.Component != tc.Component || r.Label != tc.Label {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, %q, %d, Got: %v", tc.Origin, tc.Pattern, tc.Match, tc.Component, tc.Label, r)
+		}
+		if r.Reason == "" {
+			t.Errorf("Origin: %s, Pattern: %s - Missing reason", tc.Origin, tc.Pattern)
+		}
+	}
+
+	r, err := Explain("https://a.b.example.com", "https://.example.com", MaxSubdomainDepth(1))
+	if err != nil || r.Match || r.Component != ComponentHostname || !strings.Contains(r.Reason, "limit of 1") {
+		t.Errorf("Got: %v, %v", r, err)
+	}
+
+	r, err = Explain("https://sub.example.co.uk", "https://example.com", MatchRegistrableDomain())
+	if err != nil || r.Match || r.Component != ComponentHostname {
+		t.Errorf("Got: %v, %v", r, err)
+	}
+
+	if _, err := Explain("example.com", "*"); err == nil {
+		t.Error("expected an error for an invalid origin")
+	}
+	if _, err := Explain("https://example.com", "example.com"); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,81 @@
+package origin
+
+import "net/http"
+
+// CheckFetchMetadata returns true if r is allowed by a [resource
+// isolation policy], based on the Sec-Fetch-Site, Sec-Fetch-Mode and
+// Sec-Fetch-Dest headers sent by modern browsers. The policy allows:
+//
+//   - requests without Sec-Fetch-Site header, as sent by older browsers
+//     and by clients other than browsers;
+//   - same-origin and same-site requests, and requests initiated by the
+//     user, such as by typing a URL;
+//   - top-level navigations using the GET method, unless the resource
+//     is to be embedded as an object;
+//   - cross-site requests whose origin is matched by m, which may be nil
+//     to trust no other site.
+//
+// [resource isolation policy]: https://web.dev/articles/fetch-metadata
+func CheckFetchMetadata(r *http.Request, m Matcher) bool {
+	return checkFetchMetadata(r, func(_ *http.Request, origin string) bool {
+		if m == nil {
+			return false
+		}
+		ok, err := m.MatchOrigin(origin)
+		return ok && err == nil
+	})
+}
+
+func checkFetchMetadata(r *http.Request, trusted func(r *http.Request, origin string) bool) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "", "same-origin", "same-site", "none":
+		return true
+	}
+
+	if r.Header.Get("Sec-Fetch-Mode") == "navigate" && r.Method == http.MethodGet {
+		switch r.Header.Get("Sec-Fetch-Dest") {
+		case "object", "embed":
+		default:
+			return true
+		}
+	}
+
+	origin := Get(r)
+	return origin != "" && trusted(r, origin)
+}
+
+// FetchMetadata returns a middleware rejecting with a 403 Forbidden
+// status the requests that are not allowed by the resource isolation
+// policy described in [CheckFetchMetadata], trusting the cross-site
+// requests from the origins that match patterns.
+//
+// Rejected requests are handled by the handler set with [OnReject], if
+// any. Unlike [Middleware], FetchMetadata doesn't set any CORS header, and
+// is meant to be combined with it. It panics if any of the patterns or
+// options is invalid.
+func FetchMetadata(patterns Patterns, opts ...Option) func(http.Handler) http.Handler {
+	c, err := newConfig(opts)
+	if err != nil {
+		panic("origin: FetchMetadata: " + err.Error())
+	}
+
+	compiled, err := compilePatterns(patterns, c)
+	if err != nil {
+		panic("origin: FetchMetadata: " + err.Error())
+	}
+
+	trusted := func(r *http.Request, origin string) bool {
+		ok, _ := matchAny(r, compiled, origin, c)
+		return ok
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !checkFetchMetadata(r, trusted) {
+				reject(w, r, c)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchMetadata(t *testing.T) {
+	type testCase struct {
+		Method string
+		Origin string
+		Site   string
+		Mode   string
+		Dest   string
+		Status int
+	}
+
+	var cases = []*testCase{
+		{http.MethodPost, "", "", "", "", http.StatusOK},
+		{http.MethodPost, "https://example.com", "same-origin", "cors", "empty", http.StatusOK},
+		{http.MethodPost, "https://sub.example.com", "same-site", "cors", "empty", http.StatusOK},
+		{http.MethodGet, "", "none", "navigate", "document", http.StatusOK},
+		{http.MethodGet, "", "cross-site", "navigate", "document", http.StatusOK},
+		{http.MethodGet, "", "cross-site", "navigate", "object", http.StatusForbidden},
+		{http.MethodPost, "https://example.dev", "cross-site", "navigate", "document", http.StatusForbidden},
+		{http.MethodGet, "https://example.dev", "cross-site", "no-cors", "image", http.StatusForbidden},
+		{http.MethodPost, "https://partner.example", "cross-site", "cors", "empty", http.StatusOK},
+		{http.MethodPost, "https://partner.example.dev", "cross-site", "cors", "empty", http.StatusForbidden},
+	}
+
+	h := FetchMetadata(Patterns{"https://partner.example"})(hello)
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(tc.Method, "/", nil)
+		for name, value := range map[string]string{
+			"Origin":         tc.Origin,
+			"Sec-Fetch-Site": tc.Site,
+			"Sec-Fetch-Mode": tc.Mode,
+			"Sec-Fetch-Dest": tc.Dest,
+		} {
+			if value != "" {
+				r.Header.Set(name, value)
+			}
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status {
+			t.Errorf("%s %q (%s, %s, %s) - Wanted status: %d, Got: %d", tc.Method, tc.Origin, tc.Site, tc.Mode, tc.Dest, tc.Status, w.Code)
+		}
+		if ok := CheckFetchMetadata(r, Patterns{"https://partner.example"}); ok != (tc.Status == http.StatusOK) {
+			t.Errorf("%s %q (%s, %s, %s) - CheckFetchMetadata: %v", tc.Method, tc.Origin, tc.Site, tc.Mode, tc.Dest, ok)
+		}
+	}
+}
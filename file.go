@@ -0,0 +1,188 @@
+package origin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LoadFile reads the patterns listed in the JSON, YAML or text file at
+// path, as returned by [LoadFileEntries], stripped of their
+// descriptions.
+func LoadFile(path string) (Patterns, error) {
+	entries, err := LoadFileEntries(path)
+	if err != nil {
+		return nil, err
+	}
+	return entries.Patterns(), nil
+}
+
+// LoadFileWith is like [LoadFile], but validates the patterns according
+// to the given options, such as [AllowOpaque] for a file listing the
+// pattern "null".
+func LoadFileWith(path string, opts ...Option) (Patterns, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := loadFileEntries(path, c)
+	if err != nil {
+		return nil, err
+	}
+	return entries.Patterns(), nil
+}
+
+// LoadFileEntries reads the entries listed in the JSON, YAML or text
+// file at path, depending on its extension: ".json", ".yaml", ".yml" or
+// ".txt".
+//
+// A text file lists one entry per line, as read by [Load], with blank
+// lines and comments introduced by "#" ignored. For example:
+//
+//	# Partners
+//	https://*.partner.example  # onboarded 2024-03
+//
+// A JSON or YAML document is either a list of entries, or an object
+// listing them under a "patterns" key. Each entry is either a pattern,
+// or an object with a "pattern" and an optional "description", stored
+// as the comment of the entry. For example, in YAML:
+//
+//	patterns:
+//	  - https://example.com
+//	  - pattern: https://*.partner.example
+//	    description: onboarded 2024-03
+//
+// An error is returned if any of the patterns is invalid.
+func LoadFileEntries(path string) (Entries, error) {
+	return loadFileEntries(path, defaultConfig)
+}
+
+// loadFileEntries implements LoadFileEntries, validating the patterns
+// according to the options in c.
+func loadFileEntries(path string, c *config) (Entries, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []fileEntry
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		entries, err = decodeJSON(data)
+	case ".yaml", ".yml":
+		entries, err = decodeYAML(data)
+	case ".txt":
+		entries, err = decodeText(data, c)
+	default:
+		err = fmt.Errorf("unsupported file format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	e := make(Entries, len(entries))
+	for i, entry := range entries {
+		if err := validatePattern(entry.Pattern, c); err != nil {
+			return nil, fmt.Errorf("%s: pattern #%d (%q): %w", path, i, entry.Pattern, err)
+		}
+		e[i] = Entry(entry)
+	}
+	return e, nil
+}
+
+// fileEntry is an entry of a file. In a JSON or YAML document, it is
+// written either as a plain pattern or as an object.
+type fileEntry Entry
+
+// fileObject is the object form of a fileEntry.
+type fileObject struct {
+	Pattern     string `json:"pattern" yaml:"pattern"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// fileDocument is the object form of a JSON or YAML document.
+type fileDocument struct {
+	Patterns []fileEntry `json:"patterns" yaml:"patterns"`
+}
+
+func decodeText(data []byte, c *config) ([]fileEntry, error) {
+	e, err := load(bytes.NewReader(data), c)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]fileEntry, len(e))
+	for i, entry := range e {
+		entries[i] = fileEntry(entry)
+	}
+	return entries, nil
+}
+
+func decodeJSON(data []byte) ([]fileEntry, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		var entries []fileEntry
+		err := json.Unmarshal(data, &entries)
+		return entries, err
+	}
+
+	var doc fileDocument
+	err := json.Unmarshal(data, &doc)
+	return doc.Patterns, err
+}
+
+func decodeYAML(data []byte) ([]fileEntry, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 {
+		return nil, nil
+	}
+
+	if root.Content[0].Kind == yaml.SequenceNode {
+		var entries []fileEntry
+		err := root.Content[0].Decode(&entries)
+		return entries, err
+	}
+
+	var doc fileDocument
+	err := root.Content[0].Decode(&doc)
+	return doc.Patterns, err
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface.
+func (e *fileEntry) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+		return json.Unmarshal(data, &e.Pattern)
+	}
+
+	var obj fileObject
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*e = fileEntry{Pattern: obj.Pattern, Comment: obj.Description}
+	return nil
+}
+
+// UnmarshalYAML implements the [yaml.Unmarshaler] interface.
+func (e *fileEntry) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Decode(&e.Pattern)
+	case yaml.MappingNode:
+		var obj fileObject
+		if err := node.Decode(&obj); err != nil {
+			return err
+		}
+		*e = fileEntry{Pattern: obj.Pattern, Comment: obj.Description}
+		return nil
+	}
+	return errors.New("entry must be a pattern or an object")
+}
@@ -0,0 +1,146 @@
+package origin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoadFileEntries(t *testing.T) {
+	type testCase struct {
+		Name     string
+		Content  string
+		HasError bool
+	}
+
+	var cases = []*testCase{
+		{"list.json", `["https://example.com", {"pattern": "https://*.partner.example", "description": "onboarded 2024-03"}]`, false},
+		{"object.json", `{"patterns": ["https://example.com", {"pattern": "https://*.partner.example", "description": "onboarded 2024-03"}]}`, false},
+		{"list.yaml", "- https://example.com\n- pattern: https://*.partner.example\n  description: onboarded 2024-03\n", false},
+		{"object.YML", "patterns:\n  - https://example.com\n  - pattern: https://*.partner.example\n    description: onboarded 2024-03\n", false},
+		{"list.txt", "# Trusted origins\n\nhttps://example.com\n  https://*.partner.example  # onboarded 2024-03\n", false},
+		{"invalid.txt", "https://example.com\nexample.dev\n", true},
+		{"invalid.json", `["https://example.com", "example.dev"]`, true},
+		{"missing.json", `[{"description": "no pattern"}]`, true},
+		{"malformed.yaml", "patterns: [https://example.com", true},
+		{"unsupported.toml", `patterns = ["https://example.com"]`, true},
+	}
+
+	want := Entries{
+		{Pattern: "https://example.com"},
+		{Pattern: "https://*.partner.example", Comment: "onboarded 2024-03"},
+	}
+
+	dir := t.TempDir()
+	for _, tc := range cases {
+		path := filepath.Join(dir, tc.Name)
+		if err := os.WriteFile(path, []byte(tc.Content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		entries, err := LoadFileEntries(path)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("File: %s - Error: %v", tc.Name, err)
+		}
+		if !tc.HasError && !reflect.DeepEqual(entries, want) {
+			t.Errorf("File: %s - Wanted: %v, Got: %v", tc.Name, want, entries)
+		}
+	}
+
+	if _, err := LoadFile(filepath.Join(dir, "nonexistent.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	p, err := LoadFile(filepath.Join(dir, "list.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p, want.Patterns()) {
+		t.Errorf("Wanted: %v, Got: %v", want.Patterns(), p)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "origins.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("- https://example.com\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	r, err := WatchFile(ctx, path, RefreshEvery(10*time.Millisecond, 0), OnRefreshError(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor := func(origin string) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			if ok, _ := r.MatchOrigin(origin); ok {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Errorf("Origin: %q - the file was not reloaded", origin)
+	}
+
+	version := r.version()
+	if err := r.Refresh(ctx); err != nil || r.version() != version {
+		t.Errorf("Wanted the patterns unchanged, Got: version %d (%v)", r.version(), err)
+	}
+
+	write("- https://example.com\n- https://example.dev\n")
+	waitFor("https://example.dev")
+
+	write("- example.org\n")
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expected an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the reload error was not reported")
+	}
+	if ok, _ := r.MatchOrigin("https://example.dev"); !ok {
+		t.Error("Wanted the patterns read last to remain in use")
+	}
+
+	write("- https://example.org\n")
+	waitFor("https://example.org")
+
+	// The patterns are read according to the options.
+	opaquePath := filepath.Join(t.TempDir(), "origins.txt")
+	if err := os.WriteFile(opaquePath, []byte("null\nmyapp://example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	w, err := WatchFile(ctx, opaquePath, AllowOpaque(), WithPorts(map[string]string{"myapp": "8443"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, origin := range []string{"null", "myapp://example.com:8443"} {
+		if ok, err := w.MatchOrigin(origin); !ok || err != nil {
+			t.Errorf("Origin: %q - Wanted a match, Got: %v, %v", origin, ok, err)
+		}
+	}
+	if _, err := LoadFile(opaquePath); err == nil {
+		t.Error("Wanted an error without options")
+	}
+
+	if _, err := WatchFile(ctx, filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -1,3 +1,9 @@
 module code.posterity.life/origin
 
-go 1.20
+go 1.21
+
+require (
+	golang.org/x/net v0.20.0
+	golang.org/x/text v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,319 @@
+package origin
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// LintWarning describes an issue found in a list of patterns.
+type LintWarning struct {
+	Index   int    // index of the pattern in the list
+	Pattern string // the pattern itself
+	Message string // human-readable description of the issue
+}
+
+// String returns the warning formatted for display.
+func (w LintWarning) String() string {
+	return fmt.Sprintf("pattern #%d (%q): %s", w.Index, w.Pattern, w.Message)
+}
+
+// Lint inspects the patterns in p, and reports the ones that:
+//
+//   - are invalid;
+//   - are duplicates of an earlier pattern;
+//   - can never be reached, since any origin they match is already
+//     matched by an earlier pattern;
+//   - are overly broad, such as "*" or "https://*.com".
+//
+// An empty list is returned if no issue was found.
+func (p Patterns) Lint() []LintWarning {
+	var warnings []LintWarning
+	warn := func(i int, format string, args ...any) {
+		warnings = append(warnings, LintWarning{
+			Index:   i,
+			Pattern: p[i],
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	canonical := make([]string, len(p))
+	for i, item := range p {
+		s, err := canonicalPattern(item, defaultConfig)
+		if err != nil {
+			warn(i, "%v", err)
+			continue
+		}
+		canonical[i] = s
+
+		if broad, reason := overlyBroad(item, defaultConfig); broad {
+			warn(i, "overly broad: %s", reason)
+		}
+
+		for j := 0; j < i; j++ {
+			if canonical[j] == "" {
+				continue
+			}
+			if canonical[j] == s {
+				warn(i, "duplicate of pattern #%d", j)
+				break
+			}
+			if strings.HasPrefix(p[j], negation) || strings.HasPrefix(item, negation) {
+				continue
+			}
+			if ok, _ := Covers(p[j], item); ok {
+				warn(i, "unreachable: already matched by pattern #%d", j)
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// overlyBroad returns true if pattern matches any hostname, or
+// hostnames under more than one registrable domain.
+func overlyBroad(pattern string, c *config) (bool, string) {
+	p, err := compile(pattern, c)
+	if err != nil || p.re != nil || p.deny || p.raw == Loopback {
+		return false, ""
+	}
+	if p.alts != nil {
+		p = p.alts[0]
+	}
+
+	switch {
+	case p.host.any:
+		return true, "matches any hostname"
+	case p.host.prefix.IsValid() && p.host.prefix.Bits() == 0:
+		return true, "matches any IP address"
+	case p.host.spansPublicSuffix(c):
+		return true, "matches hostnames of any domain under a public suffix"
+	}
+	return false, ""
+}
+
+// Covers returns true if pattern a matches every origin that pattern b
+// matches, such as "https://*.example.com" and "https://sub.example.com",
+// in which case b is redundant in a list holding a.
+//
+// Negated patterns are compared by the origins they deny, so that a
+// negated pattern only covers other negated patterns. Patterns in the
+// regular expression dialect only cover identical patterns. An error is
+// returned if either pattern is invalid.
+func Covers(a, b string) (bool, error) {
+	x, err := compile(a, defaultConfig)
+	if err != nil {
+		return false, err
+	}
+	y, err := compile(b, defaultConfig)
+	if err != nil {
+		return false, err
+	}
+	return x.deny == y.deny && covers(x, y), nil
+}
+
+// Redundant returns the patterns in p that can be removed without
+// changing the origins matched by the list, in order:
+//
+//   - the patterns covered by another pattern of the same kind, such as
+//     "https://sub.example.com" along with "https://*.example.com" (see
+//     [Covers]), keeping the first of equivalent patterns;
+//   - the patterns whose origins are all denied by a negated pattern.
+//
+// An error is returned if any of the patterns is invalid.
+func (p Patterns) Redundant() (Patterns, error) {
+	compiled, err := compilePatterns(p, defaultConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var redundant Patterns
+	for i, y := range compiled {
+		for j, x := range compiled {
+			if i == j || (y.deny && !x.deny) || !covers(x, y) {
+				continue
+			}
+			// Of equivalent patterns of the same kind, the first one is
+			// kept.
+			if x.deny == y.deny && j > i && covers(y, x) {
+				continue
+			}
+			redundant = append(redundant, p[i])
+			break
+		}
+	}
+	return redundant, nil
+}
+
+// covers returns true if the compiled pattern a matches every origin
+// matched by the compiled pattern b, regardless of whether they are
+// negated.
+func covers(a, b *Pattern) bool {
+	for _, y := range b.variants() {
+		covered := false
+		for _, x := range a.variants() {
+			if covered = coversVariant(x, y); covered {
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// variants returns the patterns matching the origins matched by p, one
+// per scheme of a set of schemes.
+func (p *Pattern) variants() []*Pattern {
+	if p.alts != nil {
+		return p.alts
+	}
+	return []*Pattern{p}
+}
+
+// coversVariant is like covers, for patterns without a set of schemes.
+func coversVariant(a, b *Pattern) bool {
+	x := strings.TrimPrefix(a.raw, negation)
+	y := strings.TrimPrefix(b.raw, negation)
+	switch {
+	case a.opaque || b.opaque:
+		return a.opaque && b.opaque
+	case a.re != nil || b.re != nil:
+		return x == y
+	}
+	ok, _ := subsumes(x, y, a.c)
+	return ok
+}
+
+// subsumes returns true if pattern a matches every origin matched by
+// pattern b.
+func subsumes(a, b string, c *config) (bool, error) {
+	as, ah, ap, err := splitPattern(a, c)
+	if err != nil {
+		return false, err
+	}
+	bs, bh, bp, err := splitPattern(b, c)
+	if err != nil {
+		return false, err
+	}
+
+	if as != c.wildcard && as != bs {
+		return false, nil
+	}
+	if !portsSubsume(ap, bp, c) {
+		return false, nil
+	}
+	return hostSubsumes(normalizeHost(ah), normalizeHost(bh), c), nil
+}
+
+// hostSubsumes returns true if the hostname pattern a matches every
+// hostname matched by the hostname pattern b.
+func hostSubsumes(a, b string, c *config) bool {
+	if a == c.wildcard {
+		return true
+	}
+	if b == c.wildcard {
+		return false
+	}
+
+	aPrefix, aErr := parseIPHost(a)
+	bPrefix, bErr := parseIPHost(b)
+	if aErr == nil || bErr == nil {
+		return aErr == nil && bErr == nil &&
+			aPrefix.Bits() <= bPrefix.Bits() && aPrefix.Contains(bPrefix.Addr())
+	}
+
+	x := strings.Split(a, ".")
+	y := strings.Split(b, ".")
+	xSuffix := x[0] == "" && len(x) > 1
+	ySuffix := y[0] == "" && len(y) > 1
+	xDeep := x[0] == c.wildcard+c.wildcard && len(x) > 1
+	yDeep := y[0] == c.wildcard+c.wildcard && len(y) > 1
+	if xSuffix || xDeep {
+		x = x[1:]
+	}
+	if ySuffix || yDeep {
+		y = y[1:]
+	}
+
+	switch {
+	case ySuffix && !xSuffix, yDeep && !xSuffix && !xDeep:
+		return false
+	case xSuffix || xDeep:
+		// Unless b also requires extra labels, a double wildcard in a
+		// requires b to have more labels than a.
+		min := len(x)
+		if xDeep && !yDeep {
+			min++
+		}
+		if len(y) < min {
+			return false
+		}
+		y = y[len(y)-len(x):]
+	case len(x) != len(y):
+		return false
+	}
+
+	for i := range x {
+		if x[i] == c.wildcard || x[i] == y[i] {
+			continue
+		}
+		if c.hasWildcard(y[i]) || !matchLabel(c.unescape(y[i]), x[i], c) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseIPHost parses a hostname pattern holding either an IP address,
+// or an IP network in CIDR notation. An IP address is returned as a
+// network containing only itself.
+func parseIPHost(host string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(host)
+	return prefix.Masked(), err
+}
+
+// portsSubsume returns true if the port pattern a matches every port
+// matched by the port pattern b.
+func portsSubsume(a, b string, c *config) bool {
+	if a == c.wildcard {
+		return true
+	}
+	if b == c.wildcard {
+		return false
+	}
+
+	x, err := parsePorts(a)
+	if err != nil {
+		return false
+	}
+	y, err := parsePorts(b)
+	if err != nil {
+		return false
+	}
+
+	sort.Slice(x, func(i, j int) bool { return x[i].lo < x[j].lo })
+	for _, r := range y {
+		// Look for the contiguous span of ranges in x containing r.lo,
+		// and check that it extends up to r.hi.
+		covered := r.lo
+		for _, s := range x {
+			if s.lo <= covered && covered <= s.hi {
+				covered = s.hi + 1
+			}
+			if covered > r.hi {
+				break
+			}
+		}
+		if covered <= r.hi {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+package origin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPatternsLint(t *testing.T) {
+	p := Patterns{
+		"https://example.com",
+		"https://*.example.com",
+		"https://Example.com:443",
+		"https://sub.example.com",
+		"example.dev",
+		"https://.example.dev",
+		"https://a.b.example.dev:443",
+		"http://localhost:*",
+		"http://localhost:3000",
+		"https://*.com",
+		"*",
+		"https://partner.example.org",
+		Loopback,
+		"https://user@example.com",
+	}
+
+	want := map[int]string{
+		2:  "duplicate of pattern #0",
+		3:  "unreachable: already matched by pattern #1",
+		4:  "invalid pattern: missing scheme",
+		6:  "unreachable: already matched by pattern #5",
+		8:  "unreachable: already matched by pattern #7",
+		9:  "overly broad: matches hostnames of any domain under a public suffix",
+		10: "overly broad: matches any hostname",
+		11: "unreachable: already matched by pattern #10",
+		12: "unreachable: already matched by pattern #10",
+		13: `invalid pattern: illegal hostname "user@example.com"`,
+	}
+
+	warnings := p.Lint()
+	got := make(map[int]string)
+	for _, w := range warnings {
+		if w.Pattern != p[w.Index] {
+			t.Errorf("Warning: %v - wrong pattern", w)
+		}
+		if _, ok := got[w.Index]; ok {
+			continue
+		}
+		got[w.Index] = w.Message
+	}
+
+	for i, msg := range want {
+		if got[i] != msg {
+			t.Errorf("Pattern #%d - Wanted: %q, Got: %q", i, msg, got[i])
+		}
+	}
+	for i, msg := range got {
+		if _, ok := want[i]; !ok {
+			t.Errorf("Pattern #%d - Unexpected warning: %q", i, msg)
+		}
+	}
+}
+
+func TestSubsumes(t *testing.T) {
+	type testCase struct {
+		A, B string
+		Want bool
+	}
+
+	var cases = []*testCase{
+		{"*", "https://example.com", true},
+		{"https://example.com", "*", false},
+		{"*://*.example.com:*", "https://sub.example.com:8443", true},
+		{"https://*.example.com", "https://*.example.com:*", false},
+		{"https://.example.com", "https://example.com", true},
+		{"https://.example.com", "https://*.*.example.com", true},
+		{"https://.example.com", "https://.sub.example.com", true},
+		{"https://.sub.example.com", "https://.example.com", false},
+		{"https://*.example.com", "https://.example.com", false},
+		{"https://*.example.com", "https://a.b.example.com", false},
+		{"https://*.example.com", "https://pr-*.example.com", true},
+		{"https://pr-*.example.com", "https://pr-42.example.com", true},
+		{"https://pr-*.example.com", "https://*.example.com", false},
+		{"https://pr-*.example.com", "https://pr-*-a.example.com", false},
+		{"https://**.example.com", "https://*.example.com", true},
+		{"https://**.example.com", "https://a.*.example.com", true},
+		{"https://**.example.com", "https://example.com", false},
+		{"https://**.example.com", "https://.example.com", false},
+		{"https://.example.com", "https://**.example.com", true},
+		{"https://**.example.com", "https://**.sub.example.com", true},
+		{"https://*.example.com", "https://**.example.com", false},
+		{"https://*.*.*.*", "https://93.184.216.34", false},
+		{"https://*:*", "https://93.184.216.34", true},
+		{"https://93.184.216.34", "https://93.184.216.34:443", true},
+		{"https://10.0.0.0/8", "https://10.1.0.0/16", true},
+		{"https://10.0.0.0/8", "https://10.1.2.3", true},
+		{"https://10.1.0.0/16", "https://10.0.0.0/8", false},
+		{"https://10.1.2.3", "https://10.0.0.0/8", false},
+		{"http://localhost:3000-3999", "http://localhost:3500", true},
+		{"http://localhost:3000-3499,3500-3999", "http://localhost:3100-3600", true},
+		{"http://localhost:3000-3499,3501-3999", "http://localhost:3100-3600", false},
+		{"http://localhost:3000-3999", "http://localhost:*", false},
+		{"http://localhost:80,443", "http://localhost", true},
+	}
+
+	for _, tc := range cases {
+		got, err := subsumes(tc.A, tc.B, defaultConfig)
+		if err != nil {
+			t.Errorf("A: %s, B: %s - Error: %v", tc.A, tc.B, err)
+		}
+		if got != tc.Want {
+			t.Errorf("A: %s, B: %s - Wanted: %v, Got: %v", tc.A, tc.B, tc.Want, got)
+		}
+	}
+}
+
+func TestCovers(t *testing.T) {
+	type testCase struct {
+		A, B     string
+		HasError bool
+		Want     bool
+	}
+
+	var cases = []*testCase{
+		{"https://*.example.com", "https://sub.example.com", false, true},
+		{"https://sub.example.com", "https://*.example.com", false, false},
+		{"{http,https}://*.example.com", "https://sub.example.com", false, true},
+		{"{http,https}://example.com", "{https,http}://example.com:*", false, false},
+		{"*://example.com:*", "{http,https}://example.com", false, true},
+		{"https://example.com", "{http,https}://example.com", false, false},
+		{"*", Loopback, false, true},
+		{Loopback, "http://localhost:3000", false, true},
+		{Loopback, "http://127.0.0.1:8080", false, true},
+		{Loopback, "http://example.com", false, false},
+		{"!https://*.example.com", "!https://legacy.example.com", false, true},
+		{"!https://*.example.com", "https://legacy.example.com", false, false},
+		{"https://*.example.com", "!https://legacy.example.com", false, false},
+		{"https://example.com", "example.com", true, false},
+		{"example.com", "https://example.com", true, false},
+	}
+
+	for _, tc := range cases {
+		got, err := Covers(tc.A, tc.B)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("A: %s, B: %s - Error: %v", tc.A, tc.B, err)
+		}
+		if got != tc.Want {
+			t.Errorf("A: %s, B: %s - Wanted: %v, Got: %v", tc.A, tc.B, tc.Want, got)
+		}
+	}
+}
+
+func TestPatternsRedundant(t *testing.T) {
+	p := Patterns{
+		"https://sub.example.com",
+		"https://example.com",
+		"https://*.example.com",
+		"HTTPS://Example.com:443",
+		"!https://*.legacy.example.dev",
+		"https://a.legacy.example.dev",
+		"!https://b.legacy.example.dev",
+		"{http,https}://example.org",
+		"https://example.org",
+	}
+
+	got, err := p.Redundant()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Patterns{
+		"https://sub.example.com",
+		"HTTPS://Example.com:443",
+		"https://a.legacy.example.dev",
+		"!https://b.legacy.example.dev",
+		"https://example.org",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted: %q, Got: %q", want, got)
+	}
+
+	if _, err := (Patterns{"https://example.com", "example.dev"}).Redundant(); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
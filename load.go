@@ -0,0 +1,247 @@
+package origin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// FromEnv returns the patterns listed in the environment variable
+// named by key, as parsed by [ParseList].
+//
+// An empty list is returned if the variable is unset or empty.
+func FromEnv(key string) (Patterns, error) {
+	p, err := ParseList(os.Getenv(key))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return p, nil
+}
+
+// ParseList returns the patterns listed in s, such as:
+//
+//	https://example.com, https://*.example.com
+//
+// Patterns are separated by commas and/or whitespace, and empty
+// entries are ignored. The commas within a pattern, between the schemes
+// of a set or the port numbers of a list, don't separate patterns, as
+// in:
+//
+//	{http,https}://example.com, https://example.dev:8080,8443
+//
+// Surrounding quotes, as sometimes left in
+// environment variables, are removed. An error is returned if any of
+// the patterns is invalid.
+func ParseList(s string) (Patterns, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		s = s[1 : len(s)-1]
+	}
+
+	var p Patterns
+	for _, field := range splitList(s) {
+		if err := validatePattern(field, defaultConfig); err != nil {
+			return nil, fmt.Errorf("%q: %w", field, err)
+		}
+		p = append(p, field)
+	}
+	return p, nil
+}
+
+// splitList splits s into the patterns it lists, separated by commas
+// and/or whitespace, except for the commas within a set of schemes, and
+// the ones between the port numbers of a list.
+func splitList(s string) []string {
+	var (
+		fields []string
+		start  = -1 // of the current field
+		braces = 0  // depth within sets of schemes
+	)
+	for i, r := range s {
+		separator := unicode.IsSpace(r) || (r == ',' && braces == 0 && !inPortList(s[max(start, 0):i], s[i+1:]))
+		switch {
+		case separator && start >= 0:
+			fields = append(fields, s[start:i])
+			start = -1
+		case separator:
+		case start < 0:
+			start = i
+		}
+		switch r {
+		case '{':
+			braces++
+		case '}':
+			braces = max(braces-1, 0)
+		}
+	}
+	if start >= 0 {
+		fields = append(fields, s[start:])
+	}
+	return fields
+}
+
+// inPortList returns true if a comma between field and rest separates
+// the port numbers of a list, rather than two patterns: field ends with
+// port numbers or ranges, and rest starts with a digit.
+func inPortList(field, rest string) bool {
+	if rest == "" || rest[0] < '0' || rest[0] > '9' {
+		return false
+	}
+	i := strings.LastIndexByte(field, ':')
+	if i < 0 || !strings.Contains(field, "://") || i < strings.Index(field, "://")+len("://") {
+		return false
+	}
+	ports := field[i+1:]
+	return ports != "" && strings.Trim(ports, "0123456789-,") == ""
+}
+
+// Set implements the [flag.Value] interface, appending to p the
+// patterns listed in s, as parsed by [ParseList], unless p already holds
+// an equivalent pattern. A flag can therefore be repeated, or list
+// several patterns at once:
+//
+//	var allowed origin.Patterns
+//	flag.Var(&allowed, "allow-origin", "trusted origin pattern")
+//
+// An error is returned if any of the patterns is invalid, in which case
+// p is left unchanged.
+func (p *Patterns) Set(s string) error {
+	list, err := ParseList(s)
+	if err != nil {
+		return err
+	}
+
+	q := *p
+	for _, item := range list {
+		if q, err = q.Append(item); err != nil {
+			return err
+		}
+	}
+	*p = q
+	return nil
+}
+
+// String implements the [flag.Value] interface, returning the patterns
+// in p separated by commas.
+func (p *Patterns) String() string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join(*p, ",")
+}
+
+// UnmarshalJSON implements the [json.Unmarshaler] interface, decoding
+// a list of patterns, and returning an error mentioning the first of
+// them that is invalid, if any.
+//
+// Since no option can be given, patterns are validated against the
+// default syntax: the ones that are only valid with options, such as
+// "null" with [AllowOpaque] or the "re:" patterns of [AllowRegexp], are
+// rejected. Lists holding such patterns must be decoded as strings, and
+// validated when compiled with their options, such as by [NewStore] or
+// [Middleware].
+func (p *Patterns) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	for i, item := range list {
+		if err := validatePattern(item, defaultConfig); err != nil {
+			return fmt.Errorf("pattern #%d (%q): %w", i, item, err)
+		}
+	}
+	*p = list
+	return nil
+}
+
+// Entry is a pattern annotated with a free-form comment, typically
+// describing why the origins it matches are trusted.
+type Entry struct {
+	Pattern string
+	Comment string
+}
+
+// ParseEntry parses a pattern optionally followed by a comment
+// introduced by "#", such as:
+//
+//	https://partner.example.com  # onboarded 2024-03, contact: team-x
+//
+// An error is returned if the pattern is invalid according to the
+// default options, as [Load] and [ParseList] also validate patterns.
+func ParseEntry(s string) (Entry, error) {
+	return parseEntry(s, defaultConfig)
+}
+
+// parseEntry implements ParseEntry, validating the pattern according to
+// the options in c.
+func parseEntry(s string, c *config) (Entry, error) {
+	pattern, comment, _ := strings.Cut(s, "#")
+
+	e := Entry{
+		Pattern: strings.TrimSpace(pattern),
+		Comment: strings.TrimSpace(comment),
+	}
+	if err := validatePattern(e.Pattern, c); err != nil {
+		return Entry{}, err
+	}
+	return e, nil
+}
+
+// String returns the entry formatted as expected by [ParseEntry].
+func (e Entry) String() string {
+	if e.Comment == "" {
+		return e.Pattern
+	}
+	return e.Pattern + " # " + e.Comment
+}
+
+// Entries holds a list of annotated patterns.
+type Entries []Entry
+
+// Patterns returns the patterns in e, stripped of their comments.
+func (e Entries) Patterns() Patterns {
+	p := make(Patterns, len(e))
+	for i, entry := range e {
+		p[i] = entry.Pattern
+	}
+	return p
+}
+
+// Load reads a list of entries from r, formatted as one entry per
+// line as expected by [ParseEntry].
+//
+// Blank lines and lines containing only a comment are ignored. An
+// error mentioning the line number is returned if a pattern is
+// invalid, with the default options. Files listing patterns that need
+// options to be valid can be read with [LoadFileWith].
+func Load(r io.Reader) (Entries, error) {
+	return load(r, defaultConfig)
+}
+
+// load implements Load, validating the patterns according to the
+// options in c.
+func load(r io.Reader, c *config) (Entries, error) {
+	var entries Entries
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		e, err := parseEntry(line, c)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
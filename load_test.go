@@ -0,0 +1,137 @@
+package origin
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFromEnv(t *testing.T) {
+	const key = "ORIGIN_TEST_ALLOWED_ORIGINS"
+
+	t.Setenv(key, " https://example.com, https://*.example.com\n*://localhost:* ,, ")
+	p, err := FromEnv(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Patterns{"https://example.com", "https://*.example.com", "*://localhost:*"}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Wanted: %v, Got: %v", want, p)
+	}
+
+	t.Setenv(key, "")
+	if p, err := FromEnv(key); err != nil || len(p) != 0 {
+		t.Errorf("Got: %v, %v", p, err)
+	}
+
+	t.Setenv(key, "https://example.com,example.dev")
+	if _, err := FromEnv(key); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestParseList(t *testing.T) {
+	type testCase struct {
+		Input    string
+		Patterns Patterns
+		HasError bool
+	}
+
+	var cases = []*testCase{
+		{"", nil, false},
+		{" , ,\t", nil, false},
+		{"https://a.com, https://*.b.com", Patterns{"https://a.com", "https://*.b.com"}, false},
+		{`"https://a.com, https://*.b.com"`, Patterns{"https://a.com", "https://*.b.com"}, false},
+		{"'https://a.com'", Patterns{"https://a.com"}, false},
+		{"https://a.com\nhttps://b.com,,https://c.com", Patterns{"https://a.com", "https://b.com", "https://c.com"}, false},
+		{"https://a.com:8080,8443", Patterns{"https://a.com:8080,8443"}, false},
+		{"https://a.com:3000-3999,8080, https://b.com", Patterns{"https://a.com:3000-3999,8080", "https://b.com"}, false},
+		{"https://a.com:8080,https://b.com:8443,9443", Patterns{"https://a.com:8080", "https://b.com:8443,9443"}, false},
+		{"{http,https}://a.com,{http,https}://*.b.com:8080,8443", Patterns{"{http,https}://a.com", "{http,https}://*.b.com:8080,8443"}, false},
+		{"{http, https}://a.com", nil, true},
+		{"https://a.com, 8443", nil, true},
+		{`"https://a.com`, nil, true},
+		{"https://a.com, b.com", nil, true},
+	}
+
+	for _, tc := range cases {
+		p, err := ParseList(tc.Input)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Input: %q - Error: %v", tc.Input, err)
+		}
+		if !reflect.DeepEqual(p, tc.Patterns) {
+			t.Errorf("Input: %q - Wanted: %v, Got: %v", tc.Input, tc.Patterns, p)
+		}
+	}
+}
+
+func TestPatternsFlag(t *testing.T) {
+	var p Patterns
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&p, "allow-origin", "trusted origin pattern")
+
+	err := fs.Parse([]string{
+		"-allow-origin", "https://a.com",
+		"-allow-origin", "https://*.b.com, https://c.com",
+		"-allow-origin", "{http,https}://d.com:8080,8443",
+		"-allow-origin", "https://a.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Patterns{"https://a.com", "https://*.b.com", "https://c.com", "{http,https}://d.com:8080,8443"}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Wanted: %q, Got: %q", want, p)
+	}
+	if s := p.String(); s != "https://a.com,https://*.b.com,https://c.com,{http,https}://d.com:8080,8443" {
+		t.Errorf("Got: %q", s)
+	}
+
+	if err := fs.Parse([]string{"-allow-origin", "https://d.com, d.com"}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Wanted: %q, Got: %q", want, p)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	const input = `# Trusted origins
+
+https://example.com
+https://partner.example.com  # onboarded 2024-03, contact: team-x
+	*://localhost:*#development
+`
+
+	entries, err := Load(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Entries{
+		{Pattern: "https://example.com"},
+		{Pattern: "https://partner.example.com", Comment: "onboarded 2024-03, contact: team-x"},
+		{Pattern: "*://localhost:*", Comment: "development"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("Wanted: %v, Got: %v", want, entries)
+	}
+
+	ok, err := entries.Patterns().Match("https://partner.example.com")
+	if err != nil || !ok {
+		t.Errorf("Got: %v, %v", ok, err)
+	}
+
+	_, err = Load(strings.NewReader("https://example.com\nexample.dev # typo\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("expected an error on line 2, Got: %v", err)
+	}
+
+	// Patterns are validated with the default options.
+	if _, err := Load(strings.NewReader("null\n")); !errors.Is(err, ErrOpaqueOrigin) {
+		t.Errorf("Wanted: %v, Got: %v", ErrOpaqueOrigin, err)
+	}
+}
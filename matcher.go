@@ -0,0 +1,298 @@
+package origin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Matcher is the interface implemented by types that can decide
+// whether an origin is trusted.
+//
+// MatchOrigin returns true if origin is a match, and an error if
+// origin is not a valid origin.
+type Matcher interface {
+	MatchOrigin(origin string) (bool, error)
+}
+
+// MatcherFunc is an adapter allowing the use of an ordinary function
+// as a [Matcher], such as a callback deciding whether an origin is
+// trusted. The function must return false when origin is empty.
+type MatcherFunc func(origin string) (bool, error)
+
+// MatchOrigin implements the [Matcher] interface, calling f(origin).
+func (f MatcherFunc) MatchOrigin(origin string) (bool, error) {
+	return f(origin)
+}
+
+// patternMatcher is implemented by matchers able to report which of
+// their patterns matched an origin.
+type patternMatcher interface {
+	matchPattern(origin string) (pattern string, ok bool, err error)
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (p Patterns) MatchOrigin(origin string) (bool, error) {
+	return p.Match(origin)
+}
+
+func (p Patterns) matchPattern(origin string) (string, bool, error) {
+	i, err := p.MatchIndex(origin)
+	if i < 0 || err != nil {
+		return "", false, err
+	}
+	return p[i], true, nil
+}
+
+// anyMatcher matches the origins that any of its matchers match.
+type anyMatcher []Matcher
+
+// Any returns a [Matcher] trusting the origins that any of matchers
+// trusts, such as the origins matching a list of patterns, along with
+// the ones trusted by custom logic. Matchers are evaluated in order,
+// until one of them trusts the origin.
+//
+// An error is only returned if none of the matchers trusts the origin,
+// and one of them returned an error. Any without matchers trusts no
+// origin.
+func Any(matchers ...Matcher) Matcher {
+	return anyMatcher(matchers)
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (m anyMatcher) MatchOrigin(origin string) (bool, error) {
+	var first error
+	for _, matcher := range m {
+		ok, err := matcher.MatchOrigin(origin)
+		if ok && err == nil {
+			return true, nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return false, first
+}
+
+// allMatcher matches the origins that all of its matchers match.
+type allMatcher []Matcher
+
+// All returns a [Matcher] trusting the origins that all of matchers
+// trust. Matchers are evaluated in order, until one of them doesn't
+// trust the origin, or returns an error.
+//
+// All without matchers trusts no origin, rather than any.
+func All(matchers ...Matcher) Matcher {
+	return allMatcher(matchers)
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (m allMatcher) MatchOrigin(origin string) (bool, error) {
+	if len(m) == 0 {
+		return false, nil
+	}
+	for _, matcher := range m {
+		if ok, err := matcher.MatchOrigin(origin); !ok || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// notMatcher matches the valid origins that its matcher doesn't match.
+type notMatcher struct {
+	m Matcher
+}
+
+// Not returns a [Matcher] trusting the origins that m doesn't trust,
+// provided that they are valid. Since it would otherwise trust almost
+// any origin, it is meant to be combined with [All], to exclude origins
+// from the ones trusted by other matchers:
+//
+//	origin.All(patterns, origin.Not(blocked))
+//
+// Not never trusts an empty origin, nor the opaque origin "null".
+func Not(m Matcher) Matcher {
+	return notMatcher{m}
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (m notMatcher) MatchOrigin(origin string) (bool, error) {
+	if origin == "" || origin == opaque {
+		return false, nil
+	}
+	if _, err := ParseOrigin(origin); err != nil {
+		return false, err
+	}
+	ok, err := m.m.MatchOrigin(origin)
+	if err != nil {
+		return false, err
+	}
+	return !ok, nil
+}
+
+// Instrumented is a [Matcher] counting the decisions made by the
+// matcher it wraps.
+//
+// It is safe for concurrent use, provided that the wrapped matcher is.
+type Instrumented struct {
+	m Matcher
+
+	allowed atomic.Uint64
+	denied  atomic.Uint64
+}
+
+// Instrument returns an [Instrumented] matcher wrapping m.
+func Instrument(m Matcher) *Instrumented {
+	return &Instrumented{m: m}
+}
+
+// MatchOrigin implements the [Matcher] interface.
+//
+// Origins resulting in an error are counted as denied.
+func (i *Instrumented) MatchOrigin(origin string) (bool, error) {
+	ok, err := i.m.MatchOrigin(origin)
+	if ok && err == nil {
+		i.allowed.Add(1)
+	} else {
+		i.denied.Add(1)
+	}
+	return ok, err
+}
+
+// Stats returns the number of origins checked so far, and how many of
+// them were allowed and denied.
+func (i *Instrumented) Stats() (checks, allowed, denied uint64) {
+	allowed, denied = i.allowed.Load(), i.denied.Load()
+	return allowed + denied, allowed, denied
+}
+
+// Record describes the decision made by a matcher about an origin.
+type Record struct {
+	Origin  string
+	Allowed bool
+	Pattern string // the pattern that matched, if known
+	Err     error  // the reason why the origin is invalid, if any
+}
+
+// Recorder is a [Matcher] keeping track of the latest decisions made
+// by the matcher it wraps, mostly for testing purposes.
+//
+// The pattern that matched an origin is recorded when the wrapped
+// matcher is able to report it, as [Patterns] do.
+//
+// It is safe for concurrent use, provided that the wrapped matcher is.
+type Recorder struct {
+	m Matcher
+
+	mu      sync.Mutex
+	records []Record // ring buffer
+	next    int      // index of the next record to write
+	full    bool     // whether the ring buffer wrapped around
+}
+
+// NewRecorder returns a [Recorder] wrapping m, and keeping track of the
+// last n decisions. It panics if n is not positive.
+func NewRecorder(m Matcher, n int) *Recorder {
+	if n <= 0 {
+		panic("origin: non-positive size for NewRecorder")
+	}
+	return &Recorder{m: m, records: make([]Record, n)}
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (r *Recorder) MatchOrigin(origin string) (bool, error) {
+	rec := Record{Origin: origin}
+	if pm, ok := r.m.(patternMatcher); ok {
+		rec.Pattern, rec.Allowed, rec.Err = pm.matchPattern(origin)
+	} else {
+		rec.Allowed, rec.Err = r.m.MatchOrigin(origin)
+	}
+	if rec.Err != nil {
+		rec.Allowed = false
+	}
+
+	r.mu.Lock()
+	r.records[r.next] = rec
+	r.next = (r.next + 1) % len(r.records)
+	r.full = r.full || r.next == 0
+	r.mu.Unlock()
+
+	return rec.Allowed, rec.Err
+}
+
+// Records returns the decisions recorded so far, from the oldest to
+// the most recent.
+func (r *Recorder) Records() []Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.full {
+		return append([]Record(nil), r.records[:r.next]...)
+	}
+	return append(append([]Record(nil), r.records[r.next:]...), r.records[:r.next]...)
+}
+
+// sameSite matches origins whose registrable domain is domain.
+type sameSite struct {
+	domain string
+}
+
+// SameSite returns a [Matcher] trusting any origin served over HTTP
+// or HTTPS, on any port, whose registrable domain (also known as
+// eTLD+1) is the same as the one of base.
+//
+// For example, if base is "example.com", origins such as
+// "https://example.com" and "http://sub.example.com:8080" are a match,
+// but "https://evil-example.com" and "https://example.com.attacker.com"
+// are not.
+//
+// The registrable domain is determined using the [public suffix list],
+// which is more robust than a hand-written pattern like "*.example.com".
+//
+// [public suffix list]: https://publicsuffix.org
+func SameSite(base string) (Matcher, error) {
+	base = normalizeHost(base)
+	if base == "" {
+		return nil, errors.New("base cannot be an empty string")
+	}
+	if net.ParseIP(base) != nil {
+		return nil, fmt.Errorf("invalid base %q: not a domain name", base)
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base %q: %v", base, err)
+	}
+	return &sameSite{domain: domain}, nil
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (m *sameSite) MatchOrigin(origin string) (bool, error) {
+	if origin == "" {
+		return false, nil
+	}
+
+	scheme, host, _, err := Split(origin)
+	if err != nil {
+		return false, err
+	}
+	if scheme != "http" && scheme != "https" {
+		return false, nil
+	}
+
+	host = normalizeHost(host)
+	if net.ParseIP(host) != nil {
+		return false, nil
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return false, nil
+	}
+	return domain == m.domain, nil
+}
@@ -0,0 +1,180 @@
+package origin
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSameSite(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", false, true},
+		{"http://example.com:8080", false, true},
+		{"https://sub.example.com", false, true},
+		{"https://a.sub.example.com:443", false, true},
+		{"https://evil-example.com", false, false},
+		{"https://example.com.attacker.com", false, false},
+		{"https://example.co.uk", false, false},
+		{"wss://example.com", false, false},
+		{"https://93.184.216.34", false, false},
+		{"", false, false},
+		{"example.com", true, false},
+	}
+
+	m, err := SameSite("Example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		isMatch, err := m.MatchOrigin(tc.Origin)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s - Error: %v", tc.Origin, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s - Wanted: %v, Got: %v", tc.Origin, tc.IsMatch, isMatch)
+		}
+	}
+
+	for _, base := range []string{"", "com", "co.uk", "127.0.0.1"} {
+		if _, err := SameSite(base); err == nil {
+			t.Errorf("Base: %q - expected an error", base)
+		}
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	m := Instrument(Patterns{"https://*.example.com"})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.MatchOrigin("https://sub.example.com")
+			m.MatchOrigin("https://example.dev")
+			m.MatchOrigin("example.com")
+		}()
+	}
+	wg.Wait()
+
+	checks, allowed, denied := m.Stats()
+	if checks != 30 || allowed != 10 || denied != 20 {
+		t.Errorf("Got: %d checks, %d allowed, %d denied", checks, allowed, denied)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(Patterns{"https://example.com", "https://*.example.com"}, 3)
+
+	if records := r.Records(); len(records) != 0 {
+		t.Errorf("Wanted no records, Got: %v", records)
+	}
+
+	for _, origin := range []string{"https://a.example.com", "https://example.com", "https://example.dev", "example.com"} {
+		r.MatchOrigin(origin)
+	}
+
+	records := r.Records()
+	if len(records) != 3 {
+		t.Fatalf("Wanted 3 records, Got: %v", records)
+	}
+	if rec := records[0]; rec.Origin != "https://example.com" || !rec.Allowed || rec.Pattern != "https://example.com" || rec.Err != nil {
+		t.Errorf("Got: %+v", rec)
+	}
+	if rec := records[1]; rec.Origin != "https://example.dev" || rec.Allowed || rec.Pattern != "" || rec.Err != nil {
+		t.Errorf("Got: %+v", rec)
+	}
+	if rec := records[2]; rec.Origin != "example.com" || rec.Allowed || rec.Err == nil {
+		t.Errorf("Got: %+v", rec)
+	}
+
+	m, _ := SameSite("example.com")
+	r = NewRecorder(m, 1)
+	if ok, err := r.MatchOrigin("https://sub.example.com"); !ok || err != nil {
+		t.Errorf("Got: %v, %v", ok, err)
+	}
+	if records := r.Records(); len(records) != 1 || !records[0].Allowed || records[0].Pattern != "" {
+		t.Errorf("Got: %+v", records)
+	}
+}
+
+func TestCombinators(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		HasError bool
+		IsMatch  bool
+	}
+
+	trusted := Patterns{"https://*.example.com", "https://example.dev"}
+	blocked := MustCompile("https://legacy.example.com")
+
+	var cases = map[string][]*testCase{
+		"any": {
+			{"https://sub.example.com", false, true},
+			{"https://example.dev", false, true},
+			{"https://example.org", false, true},
+			{"https://example.net", false, false},
+			{"example.com", true, false},
+		},
+		"all": {
+			{"https://sub.example.com", false, true},
+			{"https://legacy.example.com", false, false},
+			{"https://example.dev", false, false},
+			{"", false, false},
+			{"example.com", true, false},
+		},
+		"not": {
+			{"https://sub.example.com", false, false},
+			{"https://example.net", false, true},
+			{"", false, false},
+			{"null", false, false},
+			{"example.com", true, false},
+		},
+		"empty": {
+			{"https://example.com", false, false},
+		},
+	}
+
+	matchers := map[string]Matcher{
+		"any":   Any(trusted, MustCompile("https://example.org")),
+		"all":   All(trusted, Not(blocked), MustCompile("https://*.example.com")),
+		"not":   Not(trusted),
+		"empty": Any(),
+	}
+	cases["empty-all"] = cases["empty"]
+	matchers["empty-all"] = All()
+
+	for name, list := range cases {
+		for _, tc := range list {
+			isMatch, err := matchers[name].MatchOrigin(tc.Origin)
+			if hasErr := (err != nil); hasErr != tc.HasError {
+				t.Errorf("%s: Origin: %s - Error: %v", name, tc.Origin, err)
+			}
+			if tc.IsMatch != isMatch {
+				t.Errorf("%s: Origin: %s - Wanted: %v, Got: %v", name, tc.Origin, tc.IsMatch, isMatch)
+			}
+		}
+	}
+}
+
+func TestMatcherFunc(t *testing.T) {
+	m := Any(Patterns{"https://example.com"}, MatcherFunc(func(origin string) (bool, error) {
+		return origin == "https://example.dev", nil
+	}))
+
+	for origin, want := range map[string]bool{
+		"https://example.com": true,
+		"https://example.dev": true,
+		"https://example.org": false,
+	} {
+		if ok, err := m.MatchOrigin(origin); ok != want || err != nil {
+			t.Errorf("Origin: %s - Wanted: %v, Got: %v (%v)", origin, want, ok, err)
+		}
+	}
+}
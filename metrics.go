@@ -0,0 +1,72 @@
+package origin
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is the interface implemented by collectors of metrics about
+// the requests handled by the CORS middleware, registered with the
+// [WithMetrics] option. Implementations, such as one backed by
+// Prometheus counters and histograms, must be safe for concurrent use.
+type Metrics interface {
+	// CountAllowed is called for every request whose origin is allowed.
+	CountAllowed()
+
+	// CountDenied is called for every request whose origin is denied,
+	// with invalid set if the origin is malformed.
+	CountDenied(invalid bool)
+
+	// CountPreflight is called for every preflight request, whether its
+	// origin is allowed or not.
+	CountPreflight()
+
+	// ObserveMatch is called with the time taken to match the origin of
+	// every request against the patterns.
+	ObserveMatch(d time.Duration)
+}
+
+// Counters is a [Metrics] implementation holding plain counters. It
+// also implements the [expvar.Var] interface, so that it can be
+// published with [expvar.Publish].
+//
+// The zero value is ready to use.
+type Counters struct {
+	Allowed       atomic.Uint64
+	Denied        atomic.Uint64 // including the invalid origins
+	InvalidOrigin atomic.Uint64
+	Preflight     atomic.Uint64
+	MatchTime     atomic.Int64 // cumulative, in nanoseconds
+}
+
+// CountAllowed implements the [Metrics] interface.
+func (c *Counters) CountAllowed() {
+	c.Allowed.Add(1)
+}
+
+// CountDenied implements the [Metrics] interface.
+func (c *Counters) CountDenied(invalid bool) {
+	c.Denied.Add(1)
+	if invalid {
+		c.InvalidOrigin.Add(1)
+	}
+}
+
+// CountPreflight implements the [Metrics] interface.
+func (c *Counters) CountPreflight() {
+	c.Preflight.Add(1)
+}
+
+// ObserveMatch implements the [Metrics] interface.
+func (c *Counters) ObserveMatch(d time.Duration) {
+	c.MatchTime.Add(int64(d))
+}
+
+// String returns the counters formatted as a JSON object, as expected
+// by the [expvar.Var] interface.
+func (c *Counters) String() string {
+	return fmt.Sprintf(`{"allowed": %d, "denied": %d, "invalid_origin": %d, "preflight": %d, "match_seconds": %g}`,
+		c.Allowed.Load(), c.Denied.Load(), c.InvalidOrigin.Load(), c.Preflight.Load(),
+		time.Duration(c.MatchTime.Load()).Seconds())
+}
@@ -0,0 +1,411 @@
+package origin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Middleware returns a middleware enforcing a CORS policy on the
+// requests served by the handler it wraps, trusting the origins that
+// match patterns.
+//
+// Requests without an origin header are passed through as is. When
+// the origin is a match, it is reflected in the
+// Access-Control-Allow-Origin header of the response as sent, unless
+// [EchoCanonical] is set, and never replaced with "*". Otherwise, the
+// request is rejected with a 403 Forbidden status.
+//
+// With [AllowCredentials], responses also include an
+// Access-Control-Allow-Credentials header, and the origin reflected is
+// its canonical form (see [Canonicalize]), as with [EchoCanonical].
+//
+// All responses list "Origin" in their Vary header, as do preflight
+// responses with the request headers they depend on, so that shared
+// caches never serve a response meant for another origin.
+//
+// Preflight requests, sent by browsers with the OPTIONS method and an
+// Access-Control-Request-Method header, are answered directly by the
+// middleware, without calling the wrapped handler. The requested method
+// must be one of the methods allowed (see [AllowedMethods]), or the
+// request is rejected with a 403 Forbidden status, as it is when the
+// method or any of the requested headers is not a valid token. Among
+// the requested headers, only the ones allowed (see [AllowedHeaders])
+// are listed in the Access-Control-Allow-Headers header of the
+// response. Requests to a private network are only allowed with
+// [AllowPrivateNetwork]. Browsers may cache the response for the
+// duration set with [MaxAge].
+//
+// The responses to other requests from allowed origins list the headers
+// exposed with [ExposedHeaders], if any. Rejected requests are handled
+// by the handler set with [OnReject], if any, and the status of their
+// response can be set with [RejectStatus]. With [PassThrough], requests
+// from untrusted origins reach the wrapped handler instead, without any
+// CORS header. With [AllowSameHost], the HTTPS origin of the host a
+// request was sent to is trusted too, as are the origins trusted by the
+// matcher set with [TrustMatcher].
+//
+// The options apply to the parsing and matching of patterns, and to
+// the behavior of the middleware. Middleware panics if any of the
+// patterns or options is invalid.
+func Middleware(patterns Patterns, opts ...Option) func(http.Handler) http.Handler {
+	c, err := newConfig(opts)
+	if err != nil {
+		panic("origin: Middleware: " + err.Error())
+	}
+
+	compiled, err := compilePatterns(patterns, c)
+	if err != nil {
+		panic("origin: Middleware: " + err.Error())
+	}
+	return middleware([]policy{{c: c, compiled: compiled}}, c)
+}
+
+// policy is a compiled [Policy].
+type policy struct {
+	c        *config    // the options of the policy
+	compiled []*Pattern // the patterns of the policy
+	store    *Store     // holds the patterns of the policy instead, if set
+	sameHost bool       // whether the policy trusts the origin of the requested host
+}
+
+// patterns returns the compiled patterns of p.
+func (p *policy) patterns() []*Pattern {
+	if p.store != nil {
+		_, compiled := p.store.snapshot()
+		return compiled
+	}
+	return p.compiled
+}
+
+// middleware returns a middleware enforcing the first of the policies
+// trusting the origin of each request, as described in [Middleware].
+// The options in c are those shared by all the policies.
+func middleware(policies []policy, c *config) func(http.Handler) http.Handler {
+	private := false
+	for _, p := range policies {
+		private = private || p.c.private
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Responses depend on the origin header, whether it is sent
+			// or not, and whether it is allowed or not.
+			appendVary(w.Header(), "Origin")
+
+			origin := Get(r)
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if isPreflight(r) {
+				appendVary(w.Header(), "Access-Control-Request-Method", "Access-Control-Request-Headers")
+				if private {
+					appendVary(w.Header(), "Access-Control-Request-Private-Network")
+				}
+				if c.metrics != nil {
+					c.metrics.CountPreflight()
+				}
+			}
+
+			p, _ := matchPolicy(r, policies, origin, c)
+			switch {
+			case p == nil && c.passThrough && !isPreflight(r):
+				next.ServeHTTP(w, r)
+				return
+			case p == nil:
+				reject(w, r, c)
+				return
+			}
+
+			if isPreflight(r) {
+				preflight(w, r, origin, p.c)
+				return
+			}
+
+			allowOrigin(w.Header(), origin, p.c)
+			if len(p.c.exposed) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.c.exposed, ", "))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allowOrigin sets the headers of a response allowing origin, with
+// credentials if c allows them.
+func allowOrigin(h http.Header, origin string, c *config) {
+	if c.echoCanonical || c.credentials {
+		if s, err := Canonicalize(origin); err == nil {
+			origin = s
+		}
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// AppendVary adds "Origin" to the Vary header of the response w, unless
+// it is already listed, so that shared caches don't serve a response
+// allowing an origin to another one. It is meant for handlers that
+// check origins without [Middleware], which always does so.
+func AppendVary(w http.ResponseWriter) {
+	appendVary(w.Header(), "Origin")
+}
+
+// appendVary adds to the Vary header in h the header names that it
+// doesn't list yet.
+func appendVary(h http.Header, names ...string) {
+	for _, name := range names {
+		if !varies(h, name) {
+			h.Add("Vary", name)
+		}
+	}
+}
+
+// varies returns true if the Vary header in h lists name, or "*".
+func varies(h http.Header, name string) bool {
+	for _, value := range h.Values("Vary") {
+		for _, item := range strings.Split(value, ",") {
+			item = strings.TrimSpace(item)
+			if item == "*" || strings.EqualFold(item, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reject responds to the request r, rejected by the middleware, with
+// the handler set with OnReject, or with the status set with
+// RejectStatus.
+func reject(w http.ResponseWriter, r *http.Request, c *config) {
+	if c.onReject != nil {
+		c.onReject.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, http.StatusText(c.rejectStatus), c.rejectStatus)
+}
+
+// isPreflight returns true if r is a CORS preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// preflight responds to the preflight request r, sent from origin.
+func preflight(w http.ResponseWriter, r *http.Request, origin string, c *config) {
+	method := r.Header.Get("Access-Control-Request-Method")
+	names, ok := requestedHeaders(r)
+	if !ok || !validToken(method) || !(contains(c.methods, anyName) || contains(c.methods, method)) {
+		reject(w, r, c)
+		return
+	}
+
+	methods := strings.Join(c.methods, ", ")
+	if contains(c.methods, anyName) {
+		methods = method
+	}
+
+	headers := allowedHeaders(names, c)
+
+	h := w.Header()
+	allowOrigin(h, origin, c)
+	h.Set("Access-Control-Allow-Methods", methods)
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge/time.Second)))
+	if c.private && r.Header.Get("Access-Control-Request-Private-Network") == "true" {
+		h.Set("Access-Control-Allow-Private-Network", "true")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requestedHeaders returns the header names listed in the
+// Access-Control-Request-Headers header of r, and false if any of them
+// is not a valid token.
+func requestedHeaders(r *http.Request) ([]string, bool) {
+	var names []string
+	for _, value := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name == "" {
+				continue
+			}
+			if !validToken(name) {
+				return nil, false
+			}
+			names = append(names, name)
+		}
+	}
+	return names, true
+}
+
+// allowedHeaders returns the header names in names, as requested by a
+// preflight request, that c allows, in the same order. Names are
+// compared case-insensitively, and the ones that aren't allowed are
+// left out, so that the preflight request still succeeds, and only the
+// actual request fails if it sends them.
+func allowedHeaders(names []string, c *config) []string {
+	var headers []string
+	for _, name := range names {
+		if contains(c.headers, anyName) || contains(c.headers, strings.ToLower(name)) {
+			headers = append(headers, name)
+		}
+	}
+	return headers
+}
+
+// contains returns true if s is one of the values in list.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePatterns compiles each of the patterns in p.
+func compilePatterns(p Patterns, c *config) ([]*Pattern, error) {
+	compiled := make([]*Pattern, len(p))
+	for i, item := range p {
+		var err error
+		if compiled[i], err = compile(item, c); err != nil {
+			return nil, fmt.Errorf("pattern #%d (%q): %w", i, item, err)
+		}
+	}
+	return compiled, nil
+}
+
+// matchAny returns true if origin is valid and matches any of the
+// compiled patterns, and none of the negated ones. The decision is
+// reported to the hooks and metrics of c, along with r.
+func matchAny(r *http.Request, compiled []*Pattern, origin string, c *config) (bool, error) {
+	p, err := matchPolicy(r, []policy{{c: c, compiled: compiled}}, origin, c)
+	return p != nil, err
+}
+
+// matchPolicy returns the first of the policies trusting origin, as
+// matchAny does for a single list of patterns, or nil if there is none.
+// The decision is reported to the hooks of the policy if origin is
+// allowed, and to the ones of c otherwise, along with r. Metrics are
+// collected with the ones of c.
+func matchPolicy(r *http.Request, policies []policy, origin string, c *config) (*policy, error) {
+	var start time.Time
+	if c.metrics != nil {
+		start = time.Now()
+	}
+
+	var (
+		p       *policy
+		pattern string
+		err     error
+	)
+	for j := range policies {
+		compiled := policies[j].patterns()
+		var i int
+		if i, err = matchFirst(compiled, origin); err != nil {
+			break
+		}
+		if i >= 0 {
+			p, pattern = &policies[j], compiled[i].raw
+			break
+		}
+		var ok bool
+		if pattern, ok, err = policies[j].trusts(origin); ok || err != nil {
+			if ok {
+				p = &policies[j]
+			}
+			break
+		}
+		if policies[j].sameHost && r != nil && sameHost(r, origin, policies[j].c) {
+			p, pattern = &policies[j], "https://"+policies[j].c.requestHost(r)
+			break
+		}
+	}
+	if p == nil && err == nil && c.sameHost && r != nil && sameHost(r, origin, c) {
+		p, pattern = &policy{c: c}, "https://"+c.requestHost(r)
+	}
+	ok := p != nil
+
+	if c.metrics != nil {
+		c.metrics.ObserveMatch(time.Since(start))
+		if ok {
+			c.metrics.CountAllowed()
+		} else {
+			c.metrics.CountDenied(err != nil)
+		}
+	}
+	if !ok {
+		c.report(r, origin, "", false, err)
+		return nil, err
+	}
+	p.c.report(r, origin, pattern, true, nil)
+	return p, nil
+}
+
+// trusts returns true if the matcher of p, if any, trusts origin while
+// none of the negated patterns of p matches it, along with the pattern
+// trusting it when the matcher reports it.
+func (p *policy) trusts(origin string) (string, bool, error) {
+	if p.c.matcher == nil || origin == "" {
+		return "", false, nil
+	}
+	for _, pattern := range p.patterns() {
+		if !pattern.deny {
+			continue
+		}
+		if ok, _ := pattern.match(origin); ok {
+			return "", false, nil
+		}
+	}
+	if pm, ok := p.c.matcher.(patternMatcher); ok {
+		return pm.matchPattern(origin)
+	}
+	ok, err := p.c.matcher.MatchOrigin(origin)
+	return "", ok, err
+}
+
+// sameHost returns true if origin is served over HTTPS from the host
+// r was sent to, according to its host header, or to the headers set
+// by the proxies trusted by c.
+func sameHost(r *http.Request, origin string, c *config) bool {
+	o, err := ParseOrigin(origin)
+	if err != nil || o.Scheme != "https" {
+		return false
+	}
+	self, err := ParseOrigin("https://" + c.requestHost(r))
+	return err == nil && o.Equal(self)
+}
+
+// matchFirst returns the index of the first of the compiled patterns
+// matching origin, or -1 if there is none, or if origin matches any of
+// the negated patterns.
+func matchFirst(compiled []*Pattern, origin string) (int, error) {
+	if origin == "" {
+		return -1, nil
+	}
+
+	var first = -1
+	for i, p := range compiled {
+		if !p.deny && first >= 0 {
+			continue
+		}
+		ok, err := p.match(origin)
+		if err != nil {
+			return -1, err
+		}
+		switch {
+		case !ok:
+		case p.deny:
+			return -1, nil
+		default:
+			first = i
+		}
+	}
+	return first, nil
+}
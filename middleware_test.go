@@ -0,0 +1,704 @@
+package origin
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// hello is a handler writing a constant body.
+var hello = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.WriteString(w, "Hello, World!")
+})
+
+func TestMiddleware(t *testing.T) {
+	type testCase struct {
+		Origin      string
+		Status      int
+		AllowOrigin string
+	}
+
+	var cases = []*testCase{
+		{"", http.StatusOK, ""},
+		{"null", http.StatusForbidden, ""},
+		{"https://example.com", http.StatusOK, "https://example.com"},
+		{"https://sub.example.com:8443", http.StatusOK, "https://sub.example.com:8443"},
+		{"https://example.dev", http.StatusForbidden, ""},
+		{"example.com", http.StatusForbidden, ""},
+	}
+
+	h := Middleware(Patterns{"https://example.com", "https://*.example.com:*"})(hello)
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tc.Origin != "" {
+			r.Header.Set("Origin", tc.Origin)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status {
+			t.Errorf("Origin: %q - Wanted status: %d, Got: %d", tc.Origin, tc.Status, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.AllowOrigin {
+			t.Errorf("Origin: %q - Wanted Access-Control-Allow-Origin: %q, Got: %q", tc.Origin, tc.AllowOrigin, got)
+		}
+	}
+}
+
+func TestMiddlewareOpaque(t *testing.T) {
+	h := Middleware(Patterns{"https://example.com", "null"}, AllowOpaque())(hello)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Origin", "null")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Wanted status: %d, Got: %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "null" {
+		t.Errorf("Wanted Access-Control-Allow-Origin: %q, Got: %q", "null", got)
+	}
+}
+
+func TestMiddlewareCredentials(t *testing.T) {
+	type testCase struct {
+		Origin      string
+		Options     []Option
+		AllowOrigin string
+		Credentials string
+	}
+
+	var cases = []*testCase{
+		{"HTTPS://Example.com:443", nil, "HTTPS://Example.com:443", ""},
+		{"HTTPS://Example.com:443", []Option{AllowCredentials()}, "https://example.com", "true"},
+		{"https://sub.example.com:8443", []Option{AllowCredentials()}, "https://sub.example.com:8443", "true"},
+	}
+
+	for _, tc := range cases {
+		h := Middleware(Patterns{"https://example.com", "https://*.example.com:*"}, tc.Options...)(hello)
+
+		for _, method := range []string{http.MethodGet, http.MethodOptions} {
+			r := httptest.NewRequest(method, "/", nil)
+			r.Header.Set("Origin", tc.Origin)
+			r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.AllowOrigin {
+				t.Errorf("Origin: %q, Method: %s - Wanted Access-Control-Allow-Origin: %q, Got: %q", tc.Origin, method, tc.AllowOrigin, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tc.Credentials {
+				t.Errorf("Origin: %q, Method: %s - Wanted Access-Control-Allow-Credentials: %q, Got: %q", tc.Origin, method, tc.Credentials, got)
+			}
+		}
+	}
+}
+
+func TestMiddlewareEchoCanonical(t *testing.T) {
+	type testCase struct {
+		Origin    string
+		Raw       string
+		Canonical string
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", "https://example.com", "https://example.com"},
+		{"HTTPS://Example.com:443", "HTTPS://Example.com:443", "https://example.com"},
+		{"https://sub.example.com:08443", "https://sub.example.com:08443", "https://sub.example.com:8443"},
+		{"https://[0:0::1]", "https://[0:0::1]", "https://[::1]"},
+		{"null", "null", "null"},
+	}
+
+	patterns := Patterns{"https://example.com", "https://*.example.com:*", "https://[::1]", "null"}
+	raw := Middleware(patterns, AllowOpaque())(hello)
+	canonical := Middleware(patterns, AllowOpaque(), EchoCanonical())(hello)
+
+	for _, tc := range cases {
+		for _, method := range []string{http.MethodGet, http.MethodOptions} {
+			for i, want := range []string{tc.Raw, tc.Canonical} {
+				r := httptest.NewRequest(method, "/", nil)
+				r.Header.Set("Origin", tc.Origin)
+				r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+				w := httptest.NewRecorder()
+				[]http.Handler{raw, canonical}[i].ServeHTTP(w, r)
+
+				if got := w.Header().Get("Access-Control-Allow-Origin"); got != want {
+					t.Errorf("Origin: %q, Method: %s - Wanted Access-Control-Allow-Origin: %q, Got: %q", tc.Origin, method, want, got)
+				}
+			}
+		}
+	}
+}
+
+func TestMiddlewareVary(t *testing.T) {
+	type testCase struct {
+		Origin string
+		Method string
+		Vary   []string
+	}
+
+	preflight := []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}
+	var cases = []*testCase{
+		{"", "", []string{"Origin"}},
+		{"https://example.com", "", []string{"Origin"}},
+		{"https://example.dev", "", []string{"Origin"}},
+		{"https://example.com", http.MethodPut, preflight},
+		{"https://example.dev", http.MethodPut, preflight},
+	}
+
+	h := Middleware(Patterns{"https://example.com"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AppendVary(w)
+		w.Header().Add("Vary", "Accept-Encoding")
+	}))
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		if tc.Origin != "" {
+			r.Header.Set("Origin", tc.Origin)
+		}
+		if tc.Method != "" {
+			r.Header.Set("Access-Control-Request-Method", tc.Method)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		want := tc.Vary
+		if w.Code == http.StatusOK {
+			want = append(want, "Accept-Encoding")
+		}
+		if got := w.Header().Values("Vary"); !reflect.DeepEqual(got, want) {
+			t.Errorf("Origin: %q, Method: %q - Wanted Vary: %q, Got: %q", tc.Origin, tc.Method, want, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	w.Header().Set("Vary", "accept-encoding, origin")
+	AppendVary(w)
+	if got := w.Header().Values("Vary"); len(got) != 1 {
+		t.Errorf("Wanted Origin to be listed once, Got: %q", got)
+	}
+}
+
+func TestMiddlewareOnReject(t *testing.T) {
+	problem := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"title":"Untrusted origin"}`)
+	})
+	h := Middleware(Patterns{"https://example.com"}, OnReject(problem))(hello)
+
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		for origin, rejected := range map[string]bool{"https://example.dev": true, "https://example.com": false} {
+			r := httptest.NewRequest(method, "/", nil)
+			r.Header.Set("Origin", origin)
+			r.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			// DELETE is not allowed, so that preflights are always rejected.
+			want := rejected || method == http.MethodOptions
+			if got := w.Header().Get("Content-Type") == "application/problem+json"; got != want {
+				t.Errorf("Origin: %q, Method: %s - Wanted rejected: %v, Got: %d %q", origin, method, want, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestMiddlewareRejection(t *testing.T) {
+	type testCase struct {
+		Options []Option
+		Method  string
+		Status  int
+		Body    string
+	}
+
+	var cases = []*testCase{
+		{nil, http.MethodGet, http.StatusForbidden, "Forbidden\n"},
+		{[]Option{RejectStatus(http.StatusUnauthorized)}, http.MethodGet, http.StatusUnauthorized, "Unauthorized\n"},
+		{[]Option{PassThrough()}, http.MethodGet, http.StatusOK, "Hello, World!"},
+		{[]Option{PassThrough()}, http.MethodOptions, http.StatusForbidden, "Forbidden\n"},
+	}
+
+	for _, tc := range cases {
+		h := Middleware(Patterns{"https://example.com"}, tc.Options...)(hello)
+
+		r := httptest.NewRequest(tc.Method, "/", nil)
+		r.Header.Set("Origin", "https://example.dev")
+		r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status || w.Body.String() != tc.Body {
+			t.Errorf("Method: %s - Wanted: %d %q, Got: %d %q", tc.Method, tc.Status, tc.Body, w.Code, w.Body.String())
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Method: %s - Got Access-Control-Allow-Origin: %q", tc.Method, got)
+		}
+	}
+
+	if _, err := newConfig([]Option{RejectStatus(http.StatusOK)}); err == nil {
+		t.Error("Wanted an error for a successful status")
+	}
+}
+
+func TestMiddlewareSameHost(t *testing.T) {
+	type testCase struct {
+		Host   string
+		Origin string
+		Status int
+	}
+
+	var cases = []*testCase{
+		{"brand.example.net", "https://brand.example.net", http.StatusOK},
+		{"brand.example.net:443", "https://brand.example.net", http.StatusOK},
+		{"brand.example.net:8443", "https://brand.example.net:8443", http.StatusOK},
+		{"Brand.Example.net", "https://brand.example.net", http.StatusOK},
+		{"brand.example.net", "http://brand.example.net", http.StatusForbidden},
+		{"brand.example.net", "https://other.example.net", http.StatusForbidden},
+		{"brand.example.net", "https://brand.example.net:8443", http.StatusForbidden},
+		{"brand.example.net", "https://example.com", http.StatusOK},
+	}
+
+	var allowed []string
+	h := Middleware(Patterns{"https://example.com"}, AllowSameHost(), OnAllow(func(_, pattern string) {
+		allowed = append(allowed, pattern)
+	}))(hello)
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = tc.Host
+		r.Header.Set("Origin", tc.Origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status {
+			t.Errorf("Host: %s, Origin: %s - Wanted status: %d, Got: %d", tc.Host, tc.Origin, tc.Status, w.Code)
+		}
+	}
+
+	if len(allowed) != 5 || allowed[0] != "https://brand.example.net" || allowed[4] != "https://example.com" {
+		t.Errorf("Got patterns: %q", allowed)
+	}
+}
+
+func TestMiddlewareTrustMatcher(t *testing.T) {
+	type testCase struct {
+		Origin string
+		Status int
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", http.StatusOK},
+		{"https://tenant-1.example.net", http.StatusOK},
+		{"https://tenant-2.example.net", http.StatusForbidden},
+		{"https://tenant-3.example.net", http.StatusForbidden},
+		{"https://example.net", http.StatusForbidden},
+	}
+
+	tenants := MatcherFunc(func(origin string) (bool, error) {
+		return strings.HasPrefix(origin, "https://tenant-"), nil
+	})
+	h := Middleware(Patterns{
+		"https://example.com",
+		"!https://tenant-2.example.net",
+		"!https://tenant-3.example.net",
+	}, TrustMatcher(tenants))(hello)
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Origin", tc.Origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status {
+			t.Errorf("Origin: %s - Wanted status: %d, Got: %d", tc.Origin, tc.Status, w.Code)
+		}
+	}
+
+	if _, err := newConfig([]Option{TrustMatcher(nil)}); err == nil {
+		t.Error("expected an error for a nil matcher")
+	}
+}
+
+func TestMiddlewareInvalidPattern(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	Middleware(Patterns{"https://example.com", "example.dev"})
+}
+
+func TestMiddlewarePreflight(t *testing.T) {
+	type testCase struct {
+		Origin       string
+		Method       string
+		Headers      string
+		Status       int
+		AllowHeaders string
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", "PUT", "", http.StatusNoContent, ""},
+		{"https://example.com", "DELETE", "", http.StatusForbidden, ""},
+		{"https://example.com", "put", "", http.StatusForbidden, ""},
+		{"https://example.dev", "PUT", "", http.StatusForbidden, ""},
+	}
+
+	h := Middleware(Patterns{"https://example.com"},
+		AllowedMethods(http.MethodGet, http.MethodPut),
+		AllowedHeaders("Content-Type", "X-Request-ID"),
+	)(hello)
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.Header.Set("Origin", tc.Origin)
+		r.Header.Set("Access-Control-Request-Method", tc.Method)
+		if tc.Headers != "" {
+			r.Header.Set("Access-Control-Request-Headers", tc.Headers)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status {
+			t.Errorf("Origin: %q, Method: %s - Wanted status: %d, Got: %d", tc.Origin, tc.Method, tc.Status, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Headers"); got != tc.AllowHeaders {
+			t.Errorf("Origin: %q, Method: %s - Wanted Access-Control-Allow-Headers: %q, Got: %q", tc.Origin, tc.Method, tc.AllowHeaders, got)
+		}
+		if w.Code == http.StatusNoContent {
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, PUT" {
+				t.Errorf("Origin: %q, Method: %s - Got Access-Control-Allow-Methods: %q", tc.Origin, tc.Method, got)
+			}
+			if w.Body.Len() != 0 {
+				t.Errorf("Origin: %q, Method: %s - the wrapped handler was called", tc.Origin, tc.Method)
+			}
+		}
+	}
+
+	// OPTIONS requests without Access-Control-Request-Method are not
+	// preflight requests.
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "Hello, World!" {
+		t.Errorf("Got: %d, %q", w.Code, w.Body.String())
+	}
+}
+
+func TestMiddlewarePreflightHeaders(t *testing.T) {
+	type testCase struct {
+		Headers      []string
+		Status       int
+		AllowHeaders string
+	}
+
+	var cases = []*testCase{
+		{nil, http.StatusNoContent, ""},
+		{[]string{"X-Request-ID, content-type"}, http.StatusNoContent, "X-Request-ID, content-type"},
+		{[]string{"x-request-id, Authorization,X-Debug"}, http.StatusNoContent, "x-request-id"},
+		{[]string{"Authorization"}, http.StatusNoContent, ""},
+		{[]string{"CONTENT-TYPE", "authorization, X-Request-Id"}, http.StatusNoContent, "CONTENT-TYPE, X-Request-Id"},
+		{[]string{"content-type,,"}, http.StatusNoContent, "content-type"},
+		{[]string{"X-Request-ID, X Debug"}, http.StatusForbidden, ""},
+	}
+
+	h := Middleware(Patterns{"https://example.com"}, AllowedHeaders("Content-Type", "X-Request-ID"))(hello)
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		for _, value := range tc.Headers {
+			r.Header.Add("Access-Control-Request-Headers", value)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status {
+			t.Errorf("Headers: %q - Wanted status: %d, Got: %d", tc.Headers, tc.Status, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Headers"); got != tc.AllowHeaders {
+			t.Errorf("Headers: %q - Wanted Access-Control-Allow-Headers: %q, Got: %q", tc.Headers, tc.AllowHeaders, got)
+		}
+	}
+}
+
+func TestMiddlewarePreflightWildcard(t *testing.T) {
+	h := Middleware(Patterns{"https://example.com"}, AllowedMethods("*"), AllowedHeaders("*"))(hello)
+
+	for method, status := range map[string]int{
+		"PATCH":   http.StatusNoContent,
+		"PURGE":   http.StatusNoContent,
+		"GET /":   http.StatusForbidden,
+		"PATCH\t": http.StatusForbidden,
+	} {
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", method)
+		r.Header.Set("Access-Control-Request-Headers", "Authorization, X-Anything")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != status {
+			t.Errorf("Method: %q - Wanted status: %d, Got: %d", method, status, w.Code)
+			continue
+		}
+		if status != http.StatusNoContent {
+			continue
+		}
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != method {
+			t.Errorf("Method: %q - Got Access-Control-Allow-Methods: %q", method, got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Authorization, X-Anything" {
+			t.Errorf("Method: %q - Got Access-Control-Allow-Headers: %q", method, got)
+		}
+	}
+
+	for _, opt := range []Option{AllowedMethods("GET", "PU T"), AllowedHeaders("X-Request-ID", "")} {
+		if _, err := newConfig([]Option{opt}); err == nil {
+			t.Error("Wanted an error for an invalid name")
+		}
+	}
+}
+
+func TestMiddlewareExposedHeaders(t *testing.T) {
+	type testCase struct {
+		Options []Option
+		Method  string
+		Want    string
+	}
+
+	var cases = []*testCase{
+		{nil, http.MethodGet, ""},
+		{[]Option{ExposedHeaders("X-Request-ID", "Content-Length")}, http.MethodGet, "X-Request-ID, Content-Length"},
+		{[]Option{ExposedHeaders("X-Request-ID")}, http.MethodOptions, ""},
+		{[]Option{ExposedHeaders("*")}, http.MethodPost, "*"},
+	}
+
+	for _, tc := range cases {
+		h := Middleware(Patterns{"https://example.com"}, tc.Options...)(hello)
+
+		r := httptest.NewRequest(tc.Method, "/", nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Access-Control-Expose-Headers"); got != tc.Want {
+			t.Errorf("Method: %s - Wanted: %q, Got: %q", tc.Method, tc.Want, got)
+		}
+	}
+
+	for i, opts := range [][]Option{
+		{ExposedHeaders("*"), AllowCredentials()},
+		{AllowCredentials(), ExposedHeaders("*")},
+		{ExposedHeaders("X Request")},
+	} {
+		if _, err := newConfig(opts); err == nil {
+			t.Errorf("Options #%d - Wanted an error", i)
+		}
+	}
+}
+
+func TestMiddlewareGRPCWeb(t *testing.T) {
+	h := Middleware(Patterns{"https://example.com"}, GRPCWeb())(hello)
+
+	r := httptest.NewRequest(http.MethodOptions, "/echo.Echo/Say", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	r.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web,x-user-agent")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Access-Control-Allow-Headers"); w.Code != http.StatusNoContent || got != "content-type, x-grpc-web, x-user-agent" {
+		t.Errorf("Got: %d, Access-Control-Allow-Headers: %q", w.Code, got)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/echo.Echo/Say", nil)
+	r.Header.Set("Origin", "https://example.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "Grpc-Status, Grpc-Message" {
+		t.Errorf("Got Access-Control-Expose-Headers: %q", got)
+	}
+}
+
+func TestMiddlewarePrivateNetwork(t *testing.T) {
+	for _, allowed := range []bool{false, true} {
+		var opts []Option
+		if allowed {
+			opts = append(opts, AllowPrivateNetwork())
+		}
+		h := Middleware(Patterns{"https://example.com"}, opts...)(hello)
+
+		for _, requested := range []bool{false, true} {
+			r := httptest.NewRequest(http.MethodOptions, "/", nil)
+			r.Header.Set("Origin", "https://example.com")
+			r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			if requested {
+				r.Header.Set("Access-Control-Request-Private-Network", "true")
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			want := ""
+			if allowed && requested {
+				want = "true"
+			}
+			if got := w.Header().Get("Access-Control-Allow-Private-Network"); w.Code != http.StatusNoContent || got != want {
+				t.Errorf("Allowed: %v, Requested: %v - Wanted: %q, Got: %d %q", allowed, requested, want, w.Code, got)
+			}
+		}
+	}
+}
+
+func TestMiddlewareMaxAge(t *testing.T) {
+	var cases = map[string][]Option{
+		"600":   nil,
+		"0":     {MaxAge(0)},
+		"3600":  {MaxAge(time.Hour + 500*time.Millisecond)},
+		"86400": {MaxAge(7 * 24 * time.Hour)},
+	}
+
+	for want, opts := range cases {
+		h := Middleware(Patterns{"https://example.com"}, opts...)(hello)
+
+		r := httptest.NewRequest(http.MethodOptions, "/", nil)
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Access-Control-Max-Age"); got != want {
+			t.Errorf("Wanted Access-Control-Max-Age: %q, Got: %q", want, got)
+		}
+	}
+
+	if _, err := Compile("https://example.com", MaxAge(-time.Second)); err == nil {
+		t.Error("Wanted an error for a negative duration")
+	}
+}
+
+func TestMiddlewareHooks(t *testing.T) {
+	var allowed, denied []string
+	h := Middleware(Patterns{"https://*.example.com"},
+		OnAllow(func(origin, pattern string) { allowed = append(allowed, origin+" "+pattern) }),
+		OnDeny(func(origin string) { denied = append(denied, origin) }),
+	)(hello)
+
+	for _, origin := range []string{"", "https://sub.example.com", "https://example.dev", "example.com"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if want := []string{"https://sub.example.com https://*.example.com"}; !reflect.DeepEqual(allowed, want) {
+		t.Errorf("Allowed - Wanted: %v, Got: %v", want, allowed)
+	}
+	if want := []string{"https://example.dev", "example.com"}; !reflect.DeepEqual(denied, want) {
+		t.Errorf("Denied - Wanted: %v, Got: %v", want, denied)
+	}
+}
+
+func TestMiddlewareMetrics(t *testing.T) {
+	var m Counters
+	h := Middleware(Patterns{"https://example.com"}, WithMetrics(&m))(hello)
+
+	for _, origin := range []string{"", "https://example.com", "https://example.dev", "example.com"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	r := httptest.NewRequest(http.MethodOptions, "/", nil)
+	r.Header.Set("Origin", "https://example.com")
+	r.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got := m.Allowed.Load(); got != 2 {
+		t.Errorf("Allowed - Wanted: 2, Got: %d", got)
+	}
+	if got := m.Denied.Load(); got != 2 {
+		t.Errorf("Denied - Wanted: 2, Got: %d", got)
+	}
+	if got := m.InvalidOrigin.Load(); got != 1 {
+		t.Errorf("InvalidOrigin - Wanted: 1, Got: %d", got)
+	}
+	if got := m.Preflight.Load(); got != 1 {
+		t.Errorf("Preflight - Wanted: 1, Got: %d", got)
+	}
+	if !json.Valid([]byte(m.String())) {
+		t.Errorf("Invalid JSON: %s", m.String())
+	}
+}
+
+func TestMiddlewareLogger(t *testing.T) {
+	var buf strings.Builder
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	h := Middleware(Patterns{"https://*.example.com"}, WithLogger(logger))(hello)
+
+	for _, origin := range []string{"", "https://sub.example.com", "https://example.dev", "example.com"} {
+		r := httptest.NewRequest(http.MethodGet, "/api", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	want := `level=DEBUG msg="origin allowed" origin=https://sub.example.com pattern=https://*.example.com method=GET path=/api
+level=INFO msg="origin denied" origin=https://example.dev method=GET path=/api
+level=INFO msg="origin denied" origin=example.com error="invalid origin: missing scheme" method=GET path=/api
+`
+	if got := buf.String(); got != want {
+		t.Errorf("Wanted:\n%s\nGot:\n%s", want, got)
+	}
+}
+
+func TestMiddlewareOnCheck(t *testing.T) {
+	var records []Record
+	h := Middleware(Patterns{"https://*.example.com"}, OnCheck(func(r *http.Request, rec Record) {
+		if r == nil {
+			t.Error("missing request")
+		}
+		records = append(records, rec)
+	}))(hello)
+
+	for _, origin := range []string{"", "https://sub.example.com", "https://example.dev"} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	want := []Record{
+		{Origin: "https://sub.example.com", Allowed: true, Pattern: "https://*.example.com"},
+		{Origin: "https://example.dev"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("Wanted: %v, Got: %v", want, records)
+	}
+}
@@ -0,0 +1,797 @@
+package origin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Option configures how patterns are parsed and matched, and how the
+// CORS middleware behaves.
+type Option func(*config) error
+
+// config holds the settings applied when parsing and matching
+// patterns, and by the middleware.
+type config struct {
+	wildcard     string // symbol matching any value in a pattern component
+	credentials  bool   // whether matched origins are trusted with credentials
+	explicitPort bool   // whether origins must mention their port number
+	maxLabels    int    // maximum number of labels in a hostname
+	maxWildcards int    // maximum number of wildcards in a pattern
+	maxDepth     int    // maximum number of subdomain labels matched, if positive
+	regexp       bool   // whether patterns may use the regular expression dialect
+	opaque       bool   // whether the pattern "null" is allowed
+	webSockets   bool   // whether WebSocket schemes match their HTTP equivalent
+
+	originWildcards bool // whether wildcards in origins match like in patterns
+	trailingDot     bool // whether hostnames ending with a dot match without it
+
+	publicSuffixGuard bool // whether hostname wildcards may span a public suffix
+	globalGuard       bool // whether hostname wildcards may span a top-level domain
+	registrable       bool // whether hostnames match their subdomains too
+	anchored          bool // whether hostnames must have as many labels as patterns
+
+	ports   map[string]string // standard port numbers of schemes
+	methods []string          // methods allowed in preflight requests
+	headers []string          // lowercase headers allowed in preflight requests
+	exposed []string          // response headers exposed to cross-origin requests
+	private bool              // whether requests to a private network are allowed
+	maxAge  time.Duration     // how long browsers may cache preflight responses
+
+	echoCanonical bool // whether the middleware reflects the canonical origin
+
+	onAllow func(origin, pattern string) // called when an origin is allowed
+	onDeny  func(origin string)          // called when an origin is denied
+	onCheck func(*http.Request, Record)  // called when the middleware checks an origin
+	metrics Metrics                      // collects metrics about the middleware, if set
+	logger  *slog.Logger                 // logs the decisions made, if set
+
+	onReject     http.Handler // responds to the requests rejected by the middleware
+	rejectStatus int          // status of the requests rejected by the middleware
+	passThrough  bool         // whether requests from untrusted origins reach the handler
+	sameHost     bool         // whether HTTPS origins of the requested host are trusted
+
+	proxies []netip.Prefix // networks of the reverse proxies trusted with forwarding headers
+	matcher Matcher        // trusts origins in addition to the patterns, if set
+
+	refresh time.Duration // how often a Refresher fetches its patterns
+	jitter  time.Duration // the most added at random to the refresh interval
+
+	onRefreshError func(error) // called when a Refresher fails to refresh its patterns
+}
+
+// Default limits on the complexity of patterns.
+const (
+	defaultMaxLabels    = 127 // the most labels a valid domain name can have
+	defaultMaxWildcards = 16
+)
+
+// Durations for which browsers may cache preflight responses, by
+// default and at most. Browsers cap the duration themselves, at 2 hours
+// for Chromium and 24 hours for Firefox.
+const (
+	defaultMaxAge = 10 * time.Minute
+	maxMaxAge     = 24 * time.Hour
+)
+
+// defaultRefresh is how often a [Refresher] fetches its patterns by
+// default.
+const defaultRefresh = time.Minute
+
+// defaultConfig holds the settings used when no option is given.
+var defaultConfig = &config{
+	wildcard:     wildcard,
+	maxLabels:    defaultMaxLabels,
+	maxWildcards: defaultMaxWildcards,
+	ports:        knownPorts,
+	methods:      []string{http.MethodGet, http.MethodHead, http.MethodPost},
+	maxAge:       defaultMaxAge,
+	rejectStatus: http.StatusForbidden,
+	refresh:      defaultRefresh,
+}
+
+// newConfig returns the configuration resulting from applying opts
+// to the default settings.
+func newConfig(opts []Option) (*config, error) {
+	if len(opts) == 0 {
+		return defaultConfig, nil
+	}
+
+	c := *defaultConfig
+	for _, opt := range opts {
+		if err := opt(&c); err != nil {
+			return nil, err
+		}
+	}
+	if c.anchored && c.registrable {
+		return nil, errors.New("options AnchorHostnames and MatchRegistrableDomain are incompatible")
+	}
+	if c.credentials && contains(c.exposed, anyName) {
+		return nil, errors.New("exposed header \"*\" is incompatible with option AllowCredentials")
+	}
+	return &c, nil
+}
+
+// report calls the hooks of c, if any, for the decision made about
+// origin, allowed by pattern if ok, or denied because of err, if any.
+// The request r is only known when the decision is made by the
+// middleware.
+func (c *config) report(r *http.Request, origin, pattern string, ok bool, err error) {
+	if origin == "" {
+		return
+	}
+
+	switch {
+	case ok && c.onAllow != nil:
+		c.onAllow(origin, pattern)
+	case !ok && c.onDeny != nil:
+		c.onDeny(origin)
+	}
+	if r != nil && c.onCheck != nil {
+		c.onCheck(r, Record{Origin: origin, Allowed: ok, Pattern: pattern, Err: err})
+	}
+
+	if c.logger != nil {
+		c.log(r, origin, pattern, ok, err)
+	}
+}
+
+// log logs the decision made about origin with the logger of c.
+func (c *config) log(r *http.Request, origin, pattern string, ok bool, err error) {
+	ctx := context.Background()
+	level, msg := slog.LevelInfo, "origin denied"
+	if ok {
+		level, msg = slog.LevelDebug, "origin allowed"
+	}
+	if r != nil {
+		ctx = r.Context()
+	}
+	if !c.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{slog.String("origin", origin)}
+	if ok {
+		attrs = append(attrs, slog.String("pattern", pattern))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if r != nil {
+		attrs = append(attrs, slog.String("method", r.Method), slog.String("path", r.URL.Path))
+	}
+	c.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// isAny returns true if pattern is a single wildcard, or the
+// equivalent "*://*:*", both of which match any valid origin.
+func (c *config) isAny(pattern string) bool {
+	w := c.wildcard
+	return pattern == w || pattern == w+"://"+w+":"+w
+}
+
+// structuralChars lists the characters that are either part of the
+// syntax of an origin or a pattern, or valid in one of its components.
+const structuralChars = ":/?#[]@.-_~+,!{}\\"
+
+// WithWildcard sets the symbol interpreted as a wildcard in patterns,
+// in place of the default "*".
+//
+// The symbol must be a printable ASCII character that cannot appear in
+// a well-formed origin. Letters, digits and characters with a structural
+// meaning in URLs (such as ":", "/" or ".") are rejected.
+func WithWildcard(r rune) Option {
+	return func(c *config) error {
+		if r <= ' ' || r > '~' ||
+			('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') ||
+			strings.ContainsRune(structuralChars, r) {
+			return fmt.Errorf("invalid wildcard: %q", r)
+		}
+		c.wildcard = string(r)
+		return nil
+	}
+}
+
+// WithPorts registers the standard port numbers of schemes, mapping
+// each scheme to its port number, such as {"myapp": "8443"}. Origins
+// and patterns with one of these schemes may then omit their port
+// number. The standard port numbers of common web protocols, such as
+// 443 for HTTPS, can be overridden as well.
+func WithPorts(ports map[string]string) Option {
+	return func(c *config) error {
+		merged := make(map[string]string, len(c.ports)+len(ports))
+		for scheme, port := range c.ports {
+			merged[scheme] = port
+		}
+		for scheme, port := range ports {
+			scheme = strings.ToLower(scheme)
+			if !validScheme(scheme) {
+				return fmt.Errorf("invalid scheme: %q", scheme)
+			}
+			if !validPort(port) {
+				return fmt.Errorf("invalid port for scheme %q: %q", scheme, port)
+			}
+			merged[scheme] = strings.TrimLeft(port, "0")
+		}
+		c.ports = merged
+		return nil
+	}
+}
+
+// AllowCredentials indicates that the origins matched are trusted with
+// credentials, such as cookies or authorization headers.
+//
+// Patterns with a wildcard scheme (including "*") are then rejected
+// with an error, as they would also trust origins served over an
+// insecure protocol such as plain HTTP. The middleware then sends an
+// Access-Control-Allow-Credentials header along with the canonical form
+// of the origins it allows.
+func AllowCredentials() Option {
+	return func(c *config) error {
+		c.credentials = true
+		return nil
+	}
+}
+
+// MatchWebSockets makes the WebSocket protocols equivalent to their
+// HTTP counterparts when matching schemes: "https" is then equivalent
+// to "wss", and "http" to "ws". For example, "https://app.example.com"
+// then matches "wss://app.example.com", and vice versa.
+func MatchWebSockets() Option {
+	return func(c *config) error {
+		c.webSockets = true
+		return nil
+	}
+}
+
+// RequireExplicitPort rejects origins that don't explicitly mention
+// their port number with an error, instead of inferring the standard
+// port associated with their scheme. For example, "https://example.com"
+// is then invalid, while "https://example.com:443" is not.
+//
+// Note that browsers omit the port number from the origin header when
+// it is the default one for the scheme.
+func RequireExplicitPort() Option {
+	return func(c *config) error {
+		c.explicitPort = true
+		return nil
+	}
+}
+
+// MaxLabels limits the number of labels in the hostname of patterns
+// and origins to n, which must be positive. Patterns exceeding the
+// limit are rejected with an error, and origins exceeding it are never
+// a match.
+//
+// The default limit is 127, the most labels a valid domain name can
+// have.
+func MaxLabels(n int) Option {
+	return func(c *config) error {
+		if n < 1 {
+			return fmt.Errorf("invalid maximum number of labels: %d", n)
+		}
+		c.maxLabels = n
+		return nil
+	}
+}
+
+// MaxWildcards limits the number of wildcards in a pattern to n.
+// Patterns exceeding the limit are rejected with an error. A limit of
+// zero forbids wildcards altogether.
+//
+// The default limit is 16.
+func MaxWildcards(n int) Option {
+	return func(c *config) error {
+		if n < 0 {
+			return fmt.Errorf("invalid maximum number of wildcards: %d", n)
+		}
+		c.maxWildcards = n
+		return nil
+	}
+}
+
+// MaxSubdomainDepth limits to n the number of labels that a hostname
+// pattern starting with a dot or a double wildcard matches in addition
+// to its own labels, as do the patterns matching by registrable domain
+// (see [MatchRegistrableDomain]). For example, with a limit of 2,
+// "https://.example.com" matches "https://a.b.example.com", but not
+// "https://a.b.c.example.com".
+//
+// It keeps deeply nested subdomains, such as the ones that customers of
+// a shared hosting domain may create, from matching. There is no limit
+// by default.
+func MaxSubdomainDepth(n int) Option {
+	return func(c *config) error {
+		if n < 1 {
+			return fmt.Errorf("invalid maximum subdomain depth: %d", n)
+		}
+		c.maxDepth = n
+		return nil
+	}
+}
+
+// AllowRegexp enables the regular expression dialect in patterns: a
+// pattern prefixed with "re:" is then compiled with [CompileRegexp].
+// For example, `re:https://pr-\d+\.preview\.example\.com`.
+func AllowRegexp() Option {
+	return func(c *config) error {
+		c.regexp = true
+		return nil
+	}
+}
+
+// IgnoreTrailingDot makes hostnames ending with a dot, written as
+// absolute domain names such as "example.com.", equivalent to the same
+// hostnames without the dot, in both origins and patterns. For example,
+// "https://example.com." then matches "https://example.com".
+//
+// Browsers keep the trailing dot of the URL of a document in its
+// origin, so the two forms may otherwise be different origins.
+func IgnoreTrailingDot() Option {
+	return func(c *config) error {
+		c.trailingDot = true
+		return nil
+	}
+}
+
+// AllowOriginWildcards makes a wildcard making up a label of the
+// hostname of an origin match any label of the patterns, as in earlier
+// versions of this package. By default, wildcards are only meaningful
+// in patterns, and a wildcard in an origin only matches a literal
+// wildcard character.
+//
+// Origins are sent by clients, so this option lets a crafted origin
+// such as "https://*.example.com" match a narrow pattern such as
+// "https://admin.example.com". It should only be used when the origins
+// matched are trusted.
+func AllowOriginWildcards() Option {
+	return func(c *config) error {
+		c.originWildcards = true
+		return nil
+	}
+}
+
+// AllowOpaque allows the pattern "null", matching the [opaque origin]
+// sent by sandboxed documents, documents loaded from "file:" or "data:"
+// URLs, and after some cross-origin redirects. Without this option,
+// the pattern "null" is rejected with an error.
+//
+// Any document can choose to send an opaque origin, so trusting it is
+// seldom wise with credentials.
+//
+// [opaque origin]: https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Origin#directives
+func AllowOpaque() Option {
+	return func(c *config) error {
+		c.opaque = true
+		return nil
+	}
+}
+
+// DenyPublicSuffixWildcards rejects with an error the patterns whose
+// hostname matches hostnames under more than one registrable domain,
+// according to the [public suffix list]. For example, "https://*.com",
+// "https://*.co.uk", "https://.github.io" and "*" are rejected, while
+// "https://*.example.co.uk" is not.
+//
+// [public suffix list]: https://publicsuffix.org
+func DenyPublicSuffixWildcards() Option {
+	return func(c *config) error {
+		c.publicSuffixGuard = true
+		return nil
+	}
+}
+
+// DenyGlobalWildcards rejects with an [ErrGlobalWildcard] error the
+// patterns whose hostname is a wildcard, or matches any domain under a
+// top-level domain, such as "*", "https://*.com", "https://.dev" or
+// "https://**.internal", regardless of the public suffix list. For
+// example, "https://*.example.com" and "https://*.co.uk" are not
+// rejected, unlike with [DenyPublicSuffixWildcards].
+//
+// It enforces security baselines forbidding global wildcards, including
+// under private top-level domains. Patterns in the regular expression
+// dialect are not checked.
+func DenyGlobalWildcards() Option {
+	return func(c *config) error {
+		c.globalGuard = true
+		return nil
+	}
+}
+
+// MatchRegistrableDomain makes the patterns whose hostname contains no
+// wildcard also match the subdomains of that hostname, at any depth,
+// provided that they belong to the same registrable domain (also known
+// as eTLD+1), according to the [public suffix list].
+//
+// For example, "https://example.com" then matches
+// "https://sub.example.com", but not "https://example.co.uk". Patterns
+// whose hostname is itself a public suffix, such as "https://co.uk",
+// are rejected with an error.
+//
+// [public suffix list]: https://publicsuffix.org
+func MatchRegistrableDomain() Option {
+	return func(c *config) error {
+		c.registrable = true
+		return nil
+	}
+}
+
+// AnchorHostnames requires hostnames to have exactly as many labels as
+// the hostname of the patterns they match, so that no pattern matches
+// hostnames of varying depth. Patterns whose hostname starts with a dot
+// or a double wildcard, such as "https://.example.com", are then
+// rejected with an error, while "https://*.example.com" is not. A
+// hostname made of a single wildcard still matches any hostname.
+//
+// AnchorHostnames is incompatible with [MatchRegistrableDomain].
+func AnchorHostnames() Option {
+	return func(c *config) error {
+		c.anchored = true
+		return nil
+	}
+}
+
+// AllowedMethods sets the methods that cross-origin requests may use,
+// as announced in response to preflight requests. Method names are
+// case-sensitive. The method "*" allows any method, and the method
+// requested is then announced as the only one allowed.
+//
+// By default, only the GET, HEAD and POST methods are allowed. An error
+// is returned if a method name is not a valid token.
+func AllowedMethods(methods ...string) Option {
+	return func(c *config) error {
+		for _, method := range methods {
+			if method != anyName && !validToken(method) {
+				return fmt.Errorf("invalid method: %q", method)
+			}
+		}
+		c.methods = append([]string(nil), methods...)
+		return nil
+	}
+}
+
+// AllowedHeaders sets the request headers that cross-origin requests
+// may include, as announced in response to preflight requests. Header
+// names are case-insensitive. The header name "*" allows any header,
+// and the headers requested are then all announced as allowed.
+//
+// By default, no header is allowed beyond the ones that browsers
+// consider safe, which need no permission. An error is returned if a
+// header name is not a valid token.
+func AllowedHeaders(headers ...string) Option {
+	return func(c *config) error {
+		c.headers = make([]string, len(headers))
+		for i, name := range headers {
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != anyName && !validToken(name) {
+				return fmt.Errorf("invalid header name: %q", name)
+			}
+			c.headers[i] = name
+		}
+		return nil
+	}
+}
+
+// ExposedHeaders sets the response headers that the scripts sending
+// cross-origin requests may read, beyond the ones that browsers consider
+// safe, listed in the Access-Control-Expose-Headers header of the
+// responses to allowed origins. The header name "*" exposes all the
+// headers, and is incompatible with [AllowCredentials], as browsers
+// then treat it as a literal header name.
+//
+// By default, no header is exposed. An error is returned if a header
+// name is not a valid token.
+func ExposedHeaders(headers ...string) Option {
+	return func(c *config) error {
+		c.exposed = make([]string, len(headers))
+		for i, name := range headers {
+			name = strings.TrimSpace(name)
+			if !validToken(name) {
+				return fmt.Errorf("invalid header name: %q", name)
+			}
+			c.exposed[i] = name
+		}
+		return nil
+	}
+}
+
+// GRPCWeb configures the middleware for [gRPC-Web] requests, which use
+// the POST method, and include the Content-Type, X-Grpc-Web,
+// X-User-Agent and Grpc-Timeout headers. The Grpc-Status and
+// Grpc-Message headers of the responses are exposed, as gRPC-Web
+// clients read the status of the calls from them.
+//
+// It is equivalent to the [AllowedMethods], [AllowedHeaders] and
+// [ExposedHeaders] options with these values, which later options can
+// override.
+//
+// [gRPC-Web]: https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+func GRPCWeb() Option {
+	opts := []Option{
+		AllowedMethods(http.MethodPost),
+		AllowedHeaders("Content-Type", "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout"),
+		ExposedHeaders("Grpc-Status", "Grpc-Message"),
+	}
+	return func(c *config) error {
+		for _, opt := range opts {
+			if err := opt(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// anyName is the method or header name allowing any method or header.
+const anyName = "*"
+
+// validToken returns true if s is a token, as defined in [RFC 9110],
+// which method and header names must be.
+//
+// [RFC 9110]: https://www.rfc-editor.org/rfc/rfc9110#section-5.6.2
+func validToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; {
+		case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		case strings.IndexByte("!#$%&'*+-.^_`|~", b) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// MaxAge sets how long browsers may cache the responses to preflight
+// requests, sent in the Access-Control-Max-Age header, in seconds. A
+// duration of zero disables caching, so that every cross-origin request
+// needing one is preceded by a preflight request. Durations above 24
+// hours, which no browser honors, are capped.
+//
+// The default duration is 10 minutes.
+func MaxAge(d time.Duration) Option {
+	return func(c *config) error {
+		if d < 0 {
+			return fmt.Errorf("invalid maximum age: %v", d)
+		}
+		c.maxAge = min(d, maxMaxAge)
+		return nil
+	}
+}
+
+// EchoCanonical makes the middleware reflect the canonical form of the
+// origins it allows in the Access-Control-Allow-Origin header, as
+// returned by [Canonicalize], rather than the origin header as sent.
+// For example, "HTTPS://Example.com:443" is then reflected as
+// "https://example.com".
+//
+// Browsers compare the header with the origin they sent byte for byte,
+// but always send origins in their canonical form, so that the two only
+// differ for other clients.
+func EchoCanonical() Option {
+	return func(c *config) error {
+		c.echoCanonical = true
+		return nil
+	}
+}
+
+// AllowPrivateNetwork allows cross-origin requests to reach the server
+// from less private networks, as requested by browsers implementing
+// [Private Network Access]. Preflight requests with an
+// Access-Control-Request-Private-Network header are then answered with
+// an Access-Control-Allow-Private-Network header.
+//
+// By default, the latter is never sent, and browsers block such
+// requests, as when an intranet dashboard is called from a public
+// website.
+//
+// [Private Network Access]: https://wicg.github.io/private-network-access/
+func AllowPrivateNetwork() Option {
+	return func(c *config) error {
+		c.private = true
+		return nil
+	}
+}
+
+// OnAllow registers a function called with every origin allowed by
+// the middleware or by a list of patterns, along with the pattern that
+// matched. It must be safe for concurrent use.
+func OnAllow(fn func(origin, pattern string)) Option {
+	return func(c *config) error {
+		c.onAllow = fn
+		return nil
+	}
+}
+
+// OnDeny registers a function called with every origin denied by the
+// middleware or by a list of patterns, including the invalid ones, such
+// as to log them or raise alerts. It must be safe for concurrent use.
+func OnDeny(fn func(origin string)) Option {
+	return func(c *config) error {
+		c.onDeny = fn
+		return nil
+	}
+}
+
+// WithMetrics registers m to collect metrics about the requests handled
+// by the middleware, such as how many of them are denied.
+func WithMetrics(m Metrics) Option {
+	return func(c *config) error {
+		c.metrics = m
+		return nil
+	}
+}
+
+// WithLogger logs the decisions made by the middleware, or by a list
+// of patterns, with logger. Denied origins are logged at the info
+// level, along with the reason why they are invalid, if so. Allowed
+// origins are logged at the debug level, along with the pattern that
+// matched them. The method and path of the request are included when
+// known.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *config) error {
+		c.logger = logger
+		return nil
+	}
+}
+
+// Names of the attributes describing a decision, for use in traces.
+const (
+	AttrOrigin         = "origin"
+	AttrAllowed        = "origin.allowed"
+	AttrMatchedPattern = "origin.matched_pattern"
+)
+
+// OnReject sets the handler responding to the requests rejected by the
+// middleware, in place of the default 403 Forbidden status. It can
+// write a custom body, such as a problem+json document, or redirect the
+// request. The handler is called before any CORS header is set, the Vary
+// header aside.
+func OnReject(h http.Handler) Option {
+	return func(c *config) error {
+		c.onReject = h
+		return nil
+	}
+}
+
+// RejectStatus sets the status of the responses to the requests
+// rejected by the middleware, which must be a client or server error
+// status. The default status is 403 Forbidden.
+func RejectStatus(code int) Option {
+	return func(c *config) error {
+		if code < 400 || code > 599 {
+			return fmt.Errorf("invalid reject status: %d", code)
+		}
+		c.rejectStatus = code
+		return nil
+	}
+}
+
+// AllowSameHost makes the middleware trust the HTTPS origin whose host
+// and port are the ones a request was sent to, according to its host
+// header, in addition to the origins matching the patterns. For example,
+// a request to "example.com" is then allowed from the origin
+// "https://example.com", while "http://example.com" and
+// "https://example.dev" are only allowed if a pattern matches them.
+//
+// It allows serving an application from domains that aren't known in
+// advance, such as white-label deployments.
+func AllowSameHost() Option {
+	return func(c *config) error {
+		c.sameHost = true
+		return nil
+	}
+}
+
+// TrustMatcher makes the middleware trust the origins that m trusts, in
+// addition to the origins matching the patterns, such as the ones
+// accepted by a callback wrapped in a [MatcherFunc]. The matcher is only
+// consulted when no pattern matches an origin, and never for origins
+// denied by a negated pattern.
+func TrustMatcher(m Matcher) Option {
+	return func(c *config) error {
+		if m == nil {
+			return errors.New("nil matcher")
+		}
+		c.matcher = m
+		return nil
+	}
+}
+
+// RefreshEvery sets how often a [Refresher], such as the one used by
+// [ProviderMiddleware], fetches its patterns from its [Provider]. A
+// random duration of up to jitter is added to each interval, so that
+// the instances of a service don't all query the provider at once.
+//
+// The default interval is one minute, without jitter.
+func RefreshEvery(interval, jitter time.Duration) Option {
+	return func(c *config) error {
+		if interval <= 0 {
+			return fmt.Errorf("invalid refresh interval: %v", interval)
+		}
+		if jitter < 0 {
+			return fmt.Errorf("invalid refresh jitter: %v", jitter)
+		}
+		c.refresh, c.jitter = interval, jitter
+		return nil
+	}
+}
+
+// OnRefreshError registers a function called with the error of every
+// periodic refresh of a [Refresher] that fails, such as when a file
+// watched with [WatchFile] becomes invalid, while the patterns retrieved
+// last remain in use. It must be safe for concurrent use.
+func OnRefreshError(fn func(error)) Option {
+	return func(c *config) error {
+		c.onRefreshError = fn
+		return nil
+	}
+}
+
+// TrustProxies sets the reverse proxies trusted to set the Forwarded
+// header (RFC 7239), or the X-Forwarded-Proto and X-Forwarded-Host
+// headers, as IP addresses or networks in CIDR notation, such as
+// "10.0.0.0/8". The origin a request was sent to is then derived from
+// these headers when the request comes from one of these proxies, for
+// [SameOriginWith] and [AllowSameHost]. An error is returned if an
+// address or network is invalid.
+//
+// By default, no proxy is trusted, so that behind a proxy terminating
+// TLS connections, requests appear to be sent over plain HTTP.
+func TrustProxies(networks ...string) Option {
+	return func(c *config) error {
+		c.proxies = make([]netip.Prefix, len(networks))
+		for i, network := range networks {
+			prefix, err := parseIPHost(network)
+			if err != nil {
+				return fmt.Errorf("invalid proxy network: %q", network)
+			}
+			c.proxies[i] = prefix
+		}
+		return nil
+	}
+}
+
+// PassThrough makes the CORS middleware forward the requests from
+// untrusted origins to the handler it wraps, without any CORS header,
+// instead of rejecting them. Browsers then block the response from
+// being read by the script that sent the request, but the request is
+// still processed, so that the handler must not trust it with any
+// side effect.
+//
+// Preflight requests from untrusted origins are still rejected, as the
+// handler isn't meant to answer them.
+func PassThrough() Option {
+	return func(c *config) error {
+		c.passThrough = true
+		return nil
+	}
+}
+
+// OnCheck registers a function called by the middleware with every
+// request whose origin it checks, and the decision made. It must be
+// safe for concurrent use.
+//
+// It allows annotating the span of the request with the decision, for
+// example with OpenTelemetry:
+//
+//	origin.OnCheck(func(r *http.Request, rec origin.Record) {
+//		trace.SpanFromContext(r.Context()).SetAttributes(
+//			attribute.String(origin.AttrOrigin, rec.Origin),
+//			attribute.Bool(origin.AttrAllowed, rec.Allowed),
+//			attribute.String(origin.AttrMatchedPattern, rec.Pattern),
+//		)
+//	})
+func OnCheck(fn func(r *http.Request, rec Record)) Option {
+	return func(c *config) error {
+		c.onCheck = fn
+		return nil
+	}
+}
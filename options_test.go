@@ -0,0 +1,518 @@
+package origin
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWithWildcard(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		Pattern  string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"https://sub.example.com", "https://%.example.com", false, true},
+		{"https://sub.example.com:8080", "%://sub.example.com:%", false, true},
+		{"https://sub.example.com", "%", false, true},
+		{"https://sub.example.com", "%://%:%", false, true},
+		{"https://sub.example.com", "https://*.example.com", false, false},
+		{"https://sub.example.dev", "https://%.example.com", false, false},
+	}
+
+	for _, tc := range cases {
+		isMatch, err := MatchWith(tc.Origin, tc.Pattern, WithWildcard('%'))
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.IsMatch, isMatch)
+		}
+	}
+
+	for _, r := range []rune{':', '/', '.', '-', 'a', '7', ' ', 'é'} {
+		if _, err := MatchWith("https://example.com", "https://example.com", WithWildcard(r)); err == nil {
+			t.Errorf("Wildcard: %q - expected an error", r)
+		}
+	}
+}
+
+func TestAllowCredentials(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		Pattern  string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", "https://example.com", false, true},
+		{"https://sub.example.com", "https://*.example.com:*", false, true},
+		{"https://example.com", "*://example.com", true, false},
+		{"https://example.com", "*://*:*", true, false},
+		{"https://example.com", "*", true, false},
+	}
+
+	for _, tc := range cases {
+		isMatch, err := MatchWith(tc.Origin, tc.Pattern, AllowCredentials())
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.IsMatch, isMatch)
+		}
+	}
+}
+
+func TestAllowOpaque(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		Pattern  string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"null", "null", false, true},
+		{"null", "*", false, false},
+		{"null", "https://example.com", false, false},
+		{"https://example.com", "null", false, false},
+		{"example.com", "null", true, false},
+		{"null", "!null", false, false},
+		{"https://example.com", "!null", false, true},
+	}
+
+	for _, tc := range cases {
+		isMatch, err := MatchWith(tc.Origin, tc.Pattern, AllowOpaque())
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.IsMatch, isMatch)
+		}
+	}
+
+	if _, err := Match("null", "null"); !errors.Is(err, ErrOpaqueOrigin) {
+		t.Errorf("Wanted: %v, Got: %v", ErrOpaqueOrigin, err)
+	}
+
+	s, err := NewPatternSet(Patterns{"https://example.com", "null"}, AllowOpaque())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.Match("null"); !ok || err != nil {
+		t.Errorf("PatternSet - Got: %v, %v", ok, err)
+	}
+}
+
+func TestRequireExplicitPort(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		Pattern  string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com:443", "https://example.com", false, true},
+		{"https://example.com:8443", "https://example.com:*", false, true},
+		{"https://example.com", "https://example.com", true, false},
+		{"https://example.com", "*", true, false},
+	}
+
+	for _, tc := range cases {
+		isMatch, err := MatchWith(tc.Origin, tc.Pattern, RequireExplicitPort())
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.IsMatch, isMatch)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		Pattern  string
+		Options  []Option
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"https://a.b.example.com", "https://*.*.example.com", []Option{MaxLabels(4)}, false, true},
+		{"https://a.b.example.com", "https://*.*.example.com", []Option{MaxLabels(3)}, true, false},
+		{"https://a.b.example.com", "https://*.example.com", []Option{MaxLabels(3)}, false, false},
+		{"https://a.b.example.com", "*", []Option{MaxLabels(3)}, false, true},
+		{"https://a.b.example.com", "https://*.*.example.com:*", []Option{MaxWildcards(3)}, false, true},
+		{"https://a.b.example.com", "https://*.*.example.com:*", []Option{MaxWildcards(2)}, true, false},
+		{"https://example.com", "https://example.com", []Option{MaxWildcards(0)}, false, true},
+		{"https://example.com", "*", []Option{MaxWildcards(0)}, true, false},
+		{"https://example.com", "https://example.com", []Option{MaxLabels(0)}, true, false},
+		{"https://example.com", "https://example.com", []Option{MaxWildcards(-1)}, true, false},
+	}
+
+	for _, tc := range cases {
+		isMatch, err := MatchWith(tc.Origin, tc.Pattern, tc.Options...)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.IsMatch, isMatch)
+		}
+	}
+}
+
+func TestDenyPublicSuffixWildcards(t *testing.T) {
+	var cases = map[string]bool{
+		"https://example.com":          true,
+		"https://*.example.com":        true,
+		"https://*.example.co.uk":      true,
+		"https://.example.com":         true,
+		"https://a.*.example.com":      true,
+		"https://10.0.0.0/8":           true,
+		"https://com":                  true,
+		"https://*.com":                false,
+		"https://*.co.uk":              false,
+		"https://*.*.co.uk":            false,
+		"https://.com":                 false,
+		"https://.github.io":           false,
+		"https://*.github.io":          false,
+		"https://example.*":            false,
+		"https://*.*":                  false,
+		"https://*:*":                  false,
+		"*":                            false,
+		"https://*.username.github.io": true,
+	}
+
+	for pattern, valid := range cases {
+		_, err := Compile(pattern, DenyPublicSuffixWildcards())
+		if hasErr := (err != nil); hasErr == valid {
+			t.Errorf("Pattern: %s - Wanted valid: %v, Got error: %v", pattern, valid, err)
+		}
+	}
+}
+
+func TestDenyGlobalWildcards(t *testing.T) {
+	var cases = map[string]bool{
+		"https://example.com":          true,
+		"https://*.example.com":        true,
+		"https://.example.com":         true,
+		"https://**.example.com":       true,
+		"https://*.co.uk":              true,
+		"https://*.github.io":          true,
+		"https://10.0.0.0/8":           true,
+		"https://com":                  true,
+		"{http,https}://*.example.com": true,
+		"https://*.com":                false,
+		"https://*.internal":           false,
+		"https://pr-*.dev":             false,
+		"https://.com":                 false,
+		"https://**.com":               false,
+		"https://example.*":            false,
+		"https://*.*":                  false,
+		"https://*:*":                  false,
+		"{http,https}://*.com":         false,
+		"*://*:*":                      false,
+		"*":                            false,
+	}
+
+	for pattern, valid := range cases {
+		_, err := Compile(pattern, DenyGlobalWildcards())
+		if hasErr := (err != nil); hasErr == valid {
+			t.Errorf("Pattern: %s - Wanted valid: %v, Got error: %v", pattern, valid, err)
+		}
+		if err != nil && !errors.Is(err, ErrGlobalWildcard) {
+			t.Errorf("Pattern: %s - Wanted ErrGlobalWildcard, Got: %v", pattern, err)
+		}
+	}
+}
+
+func TestMaxSubdomainDepth(t *testing.T) {
+	type testCase struct {
+		Origin  string
+		Pattern string
+		IsMatch bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", "https://.example.com", true},
+		{"https://a.example.com", "https://.example.com", true},
+		{"https://a.b.example.com", "https://.example.com", true},
+		{"https://a.b.c.example.com", "https://.example.com", false},
+		{"https://a.example.com", "https://**.example.com", true},
+		{"https://a.b.example.com", "https://**.example.com", true},
+		{"https://a.b.c.example.com", "https://**.example.com", false},
+		{"https://a.b.c.example.com", "https://*.*.*.example.com", true},
+		{"https://a.b.c.example.com", "*", true},
+	}
+
+	for _, tc := range cases {
+		for _, opts := range [][]Option{
+			{MaxSubdomainDepth(2)},
+			{MaxSubdomainDepth(2), WithWildcard('%')},
+		} {
+			pattern := tc.Pattern
+			if len(opts) > 1 {
+				pattern = strings.ReplaceAll(pattern, "*", "%")
+			}
+			isMatch, err := MatchWith(tc.Origin, pattern, opts...)
+			if err != nil {
+				t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, pattern, err)
+			}
+			if isMatch != tc.IsMatch {
+				t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, pattern, tc.IsMatch, isMatch)
+			}
+		}
+	}
+
+	s, err := NewPatternSet(Patterns{"https://.example.com"}, MaxSubdomainDepth(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Match("https://a.b.example.com"); ok {
+		t.Error("Wanted no match beyond the maximum depth")
+	}
+	if ok, _ := MatchWith("https://a.b.example.com", "https://example.com", MatchRegistrableDomain(), MaxSubdomainDepth(1)); ok {
+		t.Error("Wanted no match beyond the maximum depth")
+	}
+
+	if _, err := Compile("https://.example.com", MaxSubdomainDepth(0)); err == nil {
+		t.Error("expected an error for a zero depth")
+	}
+}
+
+func TestMatchRegistrableDomain(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		Pattern  string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", "https://example.com", false, true},
+		{"https://sub.example.com", "https://example.com", false, true},
+		{"https://a.b.example.com", "https://example.com", false, true},
+		{"https://a.sub.example.com", "https://sub.example.com", false, true},
+		{"https://other.example.com", "https://sub.example.com", false, false},
+		{"http://sub.example.com", "https://example.com", false, false},
+		{"https://sub.example.com:8443", "https://example.com", false, false},
+		{"https://example.co.uk", "https://example.com", false, false},
+		{"https://evil-example.com", "https://example.com", false, false},
+		{"https://sub.example.co.uk", "https://example.co.uk", false, true},
+		{"https://user.github.io", "https://github.io", true, false},
+		{"https://a.b.example.com", "https://*.example.com", false, false},
+		{"https://10.0.0.1", "https://10.0.0.1", false, true},
+	}
+
+	for _, tc := range cases {
+		isMatch, err := MatchWith(tc.Origin, tc.Pattern, MatchRegistrableDomain())
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.IsMatch, isMatch)
+		}
+	}
+}
+
+func TestAnchorHostnames(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		Pattern  string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", "https://example.com", false, true},
+		{"https://sub.example.com", "https://*.example.com", false, true},
+		{"https://a.b.example.com", "https://*.example.com", false, false},
+		{"https://a.b.example.com", "https://*", false, true},
+		{"https://sub.example.com", "https://.example.com", true, false},
+		{"https://sub.example.com", "https://**.example.com", true, false},
+		{"https://sub.example.com", "!https://.example.com", true, false},
+	}
+
+	for _, tc := range cases {
+		isMatch, err := MatchWith(tc.Origin, tc.Pattern, AnchorHostnames())
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+		}
+		if tc.HasError && !errors.Is(err, ErrUnanchored) {
+			t.Errorf("Pattern: %s - Wanted: %v, Got: %v", tc.Pattern, ErrUnanchored, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.IsMatch, isMatch)
+		}
+	}
+
+	if _, err := Compile("https://example.com", AnchorHostnames(), MatchRegistrableDomain()); err == nil {
+		t.Error("Wanted an error for incompatible options")
+	}
+}
+
+func TestIgnoreTrailingDot(t *testing.T) {
+	type testCase struct {
+		Origin  string
+		Pattern string
+		Strict  bool
+		Lax     bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com.", "https://example.com", false, true},
+		{"https://example.com.:443", "https://example.com", false, true},
+		{"https://sub.example.com.", "https://*.example.com", false, true},
+		{"https://sub.example.com.", "https://.example.com", false, true},
+		{"https://example.com", "https://example.com.", false, true},
+		{"https://example.com.", "https://example.com.", true, true},
+		{"https://example.com..", "https://example.com", false, false},
+	}
+
+	for _, tc := range cases {
+		if ok, _ := Match(tc.Origin, tc.Pattern); ok != tc.Strict {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.Strict, ok)
+		}
+		if ok, _ := MatchWith(tc.Origin, tc.Pattern, IgnoreTrailingDot()); ok != tc.Lax {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted with option: %v, Got: %v", tc.Origin, tc.Pattern, tc.Lax, ok)
+		}
+	}
+
+	s, err := NewPatternSet(Patterns{"https://example.com"}, IgnoreTrailingDot())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := s.Match("https://example.com."); !ok || err != nil {
+		t.Errorf("Wanted a match from the set, Got: %v (%v)", ok, err)
+	}
+}
+
+func TestAllowOriginWildcards(t *testing.T) {
+	const origin = "https://*.example.com"
+	p := Patterns{"https://admin.example.com"}
+
+	for _, lax := range []bool{false, true} {
+		var opts []Option
+		if lax {
+			opts = append(opts, AllowOriginWildcards())
+		}
+
+		if ok, err := p.MatchWith(origin, opts...); ok != lax || err != nil {
+			t.Errorf("Lax: %v - Wanted: %v, Got: %v (%v)", lax, lax, ok, err)
+		}
+
+		s, err := NewPatternSet(p, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := s.Match(origin); ok != lax || err != nil {
+			t.Errorf("Lax: %v - Wanted from set: %v, Got: %v (%v)", lax, lax, ok, err)
+		}
+	}
+}
+
+func TestDecisionHooks(t *testing.T) {
+	var decisions []string
+	opts := []Option{
+		OnAllow(func(origin, pattern string) { decisions = append(decisions, "allow "+origin+" "+pattern) }),
+		OnDeny(func(origin string) { decisions = append(decisions, "deny "+origin) }),
+	}
+	p := Patterns{"https://example.com", "https://*.example.com"}
+
+	for _, origin := range []string{"https://sub.example.com", "https://example.dev", ""} {
+		p.MatchWith(origin, opts...)
+	}
+
+	s, err := NewPatternSet(p, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Match("https://example.com")
+
+	want := []string{
+		"allow https://sub.example.com https://*.example.com",
+		"deny https://example.dev",
+		"allow https://example.com https://example.com",
+	}
+	if !reflect.DeepEqual(decisions, want) {
+		t.Errorf("Wanted: %v, Got: %v", want, decisions)
+	}
+}
+
+func TestWithPorts(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		Pattern  string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"myapp://example.com", "myapp://example.com:8443", false, true},
+		{"myapp://example.com:8443", "myapp://example.com", false, true},
+		{"https://example.com", "https://example.com:8443", false, true},
+		{"https://example.com:443", "https://example.com", false, false},
+		{"ftp://example.com:21", "ftp://example.com", false, true},
+		{"other://example.com", "*", true, false},
+	}
+
+	opt := WithPorts(map[string]string{"MyApp": "8443", "https": "8443"})
+	for _, tc := range cases {
+		isMatch, err := MatchWith(tc.Origin, tc.Pattern, opt)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.IsMatch, isMatch)
+		}
+	}
+
+	for _, ports := range []map[string]string{{"my app": "80"}, {"myapp": "0"}, {"myapp": "http"}} {
+		if _, err := MatchWith("https://example.com", "*", WithPorts(ports)); err == nil {
+			t.Errorf("Ports: %v - expected an error", ports)
+		}
+	}
+
+	if _, err := Match("myapp://example.com", "*"); err == nil {
+		t.Error("WithPorts must not affect the default settings")
+	}
+}
+
+func TestMatchWebSockets(t *testing.T) {
+	type testCase struct {
+		Origin  string
+		Pattern string
+		IsMatch bool
+	}
+
+	var cases = []*testCase{
+		{"wss://app.example.com", "https://app.example.com", true},
+		{"https://app.example.com", "wss://app.example.com", true},
+		{"ws://localhost:3000", "http://localhost:3000", true},
+		{"WSS://app.example.com", "HTTPS://app.example.com", true},
+		{"ws://app.example.com", "https://app.example.com", false},
+		{"wss://app.example.com:8443", "https://app.example.com", false},
+		{"ftp://app.example.com", "https://app.example.com", false},
+	}
+
+	for _, tc := range cases {
+		isMatch, err := MatchWith(tc.Origin, tc.Pattern, MatchWebSockets())
+		if err != nil {
+			t.Errorf("Origin: %s, Pattern: %s - Error: %v", tc.Origin, tc.Pattern, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s, Pattern: %s - Wanted: %v, Got: %v", tc.Origin, tc.Pattern, tc.IsMatch, isMatch)
+		}
+	}
+
+	if ok, _ := Match("wss://app.example.com", "https://app.example.com"); ok {
+		t.Error("WebSocket schemes must not match by default")
+	}
+}
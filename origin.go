@@ -7,27 +7,35 @@
 package origin // code.posterity.life/origin
 
 import (
-	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/netip"
 	"net/url"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
-)
 
-// wildcard symbols.
-const (
-	wildcard = "*"
-	anyValue = "*://*:*"
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+	"golang.org/x/text/unicode/norm"
 )
 
+// wildcard is the default wildcard symbol.
+const wildcard = "*"
+
+// opaque is the serialization of an opaque origin, sent by sandboxed
+// documents or after some cross-origin redirects.
+const opaque = "null"
+
 // Standard ports for common web protocols.
 var knownPorts = map[string]string{
 	"https":  "443",
 	"wss":    "443",
 	"http":   "80",
 	"ws":     "80",
-	"ftp":    "23",
+	"ftp":    "21",
 	"gopher": "70",
 }
 
@@ -36,38 +44,201 @@ var knownPorts = map[string]string{
 // if origin doesn't explicitly mention one. For example,
 // "https://example.com" will return "https", "example.com" and
 // "443" as the port.
+//
+// Unlike a URL, an origin cannot have user information, a path, a query
+// or a fragment, and is rejected with an error if it does.
 func Split(origin string) (scheme, host, port string, err error) {
-	var u *url.URL
+	scheme, host, port, _, err = split(origin, defaultConfig)
+	return
+}
 
-	u, err = url.Parse(origin)
-	if err != nil {
-		err = fmt.Errorf("invalid origin: %v", err)
-		return
+// SplitDetailed is like [Split], but also reports whether the port
+// was inferred from the scheme, rather than explicitly mentioned in
+// origin.
+func SplitDetailed(origin string) (scheme, host, port string, portInferred bool, err error) {
+	return split(origin, defaultConfig)
+}
+
+func split(origin string, c *config) (scheme, host, port string, inferred bool, err error) {
+	var reason error
+	scheme, host, port, reason = parseOrigin(origin)
+	if c.trailingDot && len(host) > 1 {
+		host = strings.TrimSuffix(host, ".")
 	}
+	if reason == nil && port == "" {
+		port, inferred = c.ports[scheme]
+		if !inferred || c.explicitPort {
+			port, inferred, reason = "", false, ErrMissingPort
+		}
+	}
+	if reason != nil {
+		return "", "", "", false, &ErrInvalidOrigin{Origin: origin, Reason: reason}
+	}
+	return scheme, host, port, inferred, nil
+}
 
-	scheme, host, port = u.Scheme, u.Hostname(), u.Port()
-	if scheme == "" {
-		err = errors.New("invalid origin: missing scheme")
-		return
+// parseOrigin splits origin, formatted as scheme "://" host [":" port],
+// into its components. The scheme is lowercased, and the brackets
+// enclosing an IPv6 address are removed. Unlike [url.Parse], it rejects
+// the user information, path, query and fragment that a URL may have,
+// but an origin may not.
+//
+// The error returned, if any, is the reason why origin is invalid.
+func parseOrigin(origin string) (scheme, host, port string, err error) {
+	scheme, rest, ok := strings.Cut(origin, "://")
+	if !ok || scheme == "" {
+		return "", "", "", ErrMissingScheme
+	}
+	scheme = lower(scheme)
+	if !validScheme(scheme) {
+		return "", "", "", fmt.Errorf("%w %q", ErrIllegalScheme, scheme)
 	}
 
-	if port == "" {
-		var ok bool
-		port, ok = knownPorts[scheme]
-		if !ok {
-			err = errors.New("invalid origin: missing port")
-			return
+	if strings.HasPrefix(rest, "[") {
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return "", "", "", fmt.Errorf("%w: missing ']'", ErrIllegalHostname)
+		}
+		host, rest = rest[1:end], rest[end+1:]
+		if addr, err := netip.ParseAddr(host); err != nil || !addr.Is6() {
+			return "", "", "", fmt.Errorf("%w %q", ErrIllegalHostname, host)
+		}
+		if rest != "" && rest[0] != ':' {
+			return "", "", "", fmt.Errorf("%w %q", ErrIllegalHostname, host+rest)
+		}
+	} else {
+		i := strings.IndexByte(rest, ':')
+		if i < 0 {
+			i = len(rest)
+		}
+		host, rest = rest[:i], rest[i:]
+		if host == "" {
+			return "", "", "", ErrMissingHostname
+		}
+		for i := 0; i < len(host); i++ {
+			if b := host[i]; b <= ' ' || b == 0x7f || strings.IndexByte(`/?#@[]\%`, b) >= 0 {
+				return "", "", "", fmt.Errorf("%w %q", ErrIllegalHostname, host)
+			}
 		}
 	}
 
-	return
+	if rest != "" {
+		port = rest[1:]
+		if !validPort(port) {
+			return "", "", "", fmt.Errorf("%w %q", ErrIllegalPort, port)
+		}
+	}
+	return scheme, host, port, nil
+}
+
+// lower is like strings.ToLower, but only allocates when s contains
+// uppercase ASCII letters.
+func lower(s string) string {
+	for i := 0; i < len(s); i++ {
+		if 'A' <= s[i] && s[i] <= 'Z' {
+			return strings.ToLower(s)
+		}
+	}
+	return s
+}
+
+// IsValid returns true if origin is a well-formed ASCII serialization
+// of an origin, as specified in [RFC 6454], section 6.2:
+//
+//	scheme://host[:port]
+//
+// The scheme and host must be lowercase ASCII, and the port may only be
+// omitted if the scheme has a known standard port number. Unlike [Split],
+// origins with a path, a query, a fragment or user information are
+// reported as invalid.
+//
+// Any origin reported as valid is accepted by [Split].
+//
+// [RFC 6454]: https://www.rfc-editor.org/rfc/rfc6454#section-6.2
+func IsValid(origin string) bool {
+	scheme, rest, ok := strings.Cut(origin, "://")
+	if !ok || !validScheme(scheme) {
+		return false
+	}
+
+	var host, port string
+	if strings.HasPrefix(rest, "[") {
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return false
+		}
+		addr, err := netip.ParseAddr(rest[1:end])
+		if err != nil || !addr.Is6() || addr.Zone() != "" {
+			return false
+		}
+		if rest = rest[end+1:]; rest != "" {
+			if rest[0] != ':' {
+				return false
+			}
+			port = rest[1:]
+			if port == "" {
+				return false
+			}
+		}
+	} else {
+		host = rest
+		if i := strings.LastIndexByte(rest, ':'); i >= 0 {
+			host, port = rest[:i], rest[i+1:]
+			if port == "" {
+				return false
+			}
+		}
+		if !validHostname(host) {
+			return false
+		}
+	}
+
+	if port == "" {
+		_, ok := knownPorts[scheme]
+		return ok
+	}
+	return validPort(port)
+}
+
+// validHostname returns true if host is a non-empty sequence of
+// dot-separated labels made of lowercase ASCII letters, digits, hyphens
+// and underscores.
+func validHostname(host string) bool {
+	empty := true // whether the current label is empty
+	for i := 0; i < len(host); i++ {
+		switch b := host[i]; {
+		case b == '.':
+			if empty {
+				return false
+			}
+			empty = true
+		case 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_':
+			empty = false
+		default:
+			return false
+		}
+	}
+	return !empty
 }
 
 // splitPattern is similar to Split, but supports wildcard characters
 // in scheme, hostname and port.
-func splitPattern(pattern string) (scheme, host, port string, err error) {
-	if pattern == wildcard {
-		scheme, host, port = wildcard, wildcard, wildcard
+func splitPattern(pattern string, c *config) (scheme, host, port string, err error) {
+	fail := func(pos int, reason error) {
+		err = &ErrInvalidPattern{Pattern: pattern, Pos: pos, Reason: reason}
+	}
+
+	if n := countWildcards(pattern, c.wildcard); n > c.maxWildcards {
+		fail(indexWildcard(pattern, c.wildcard, c.maxWildcards+1), fmt.Errorf("%w (%d > %d)", ErrTooManyWildcards, n, c.maxWildcards))
+		return
+	}
+
+	if c.isAny(pattern) {
+		if c.credentials {
+			fail(0, ErrWildcardScheme)
+			return
+		}
+		scheme, host, port = c.wildcard, c.wildcard, c.wildcard
 		return
 	}
 
@@ -75,67 +246,376 @@ func splitPattern(pattern string) (scheme, host, port string, err error) {
 
 	parts := strings.SplitN(pattern, sep, 2)
 	if len(parts) != 2 {
-		err = fmt.Errorf("invalid pattern: missing scheme")
+		fail(0, ErrMissingScheme)
+		return
 	}
 
-	scheme, host = parts[0], parts[1]
+	scheme, host = strings.ToLower(parts[0]), parts[1]
+	if scheme == c.wildcard {
+		if c.credentials {
+			fail(0, ErrWildcardScheme)
+			return
+		}
+	} else if i := indexWildcard(scheme, c.wildcard, 1); i >= 0 {
+		fail(i, ErrStrayWildcard)
+		return
+	} else if !validScheme(scheme) {
+		fail(0, fmt.Errorf("%w %q", ErrIllegalScheme, scheme))
+		return
+	}
 
-	if strings.Contains(host, ":") {
-		host, port, err = net.SplitHostPort(host)
-		if err != nil {
-			err = fmt.Errorf("invalid pattern: %v", err)
+	hostPos := len(parts[0]) + len(sep)
+	for i := 0; i < len(host); i++ {
+		if host[i] != escape[0] {
+			continue
+		}
+		if !strings.HasPrefix(host[i+1:], c.wildcard) {
+			fail(hostPos+i, ErrInvalidEscape)
+			return
+		}
+		i += len(c.wildcard)
+	}
+
+	switch {
+	case strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]"):
+		// IPv6 address without port number.
+		host = host[1 : len(host)-1]
+	case strings.Contains(host, ":"):
+		portPos := hostPos + strings.LastIndex(host, ":") + 1
+
+		var serr error
+		host, port, serr = net.SplitHostPort(host)
+		if serr != nil {
+			fail(hostPos, serr)
+			return
+		}
+		if port != c.wildcard {
+			if i := indexWildcard(port, c.wildcard, 1); i >= 0 {
+				fail(portPos+i, ErrStrayWildcard)
+				return
+			}
+			if _, perr := parsePorts(port); perr != nil {
+				fail(portPos, perr)
+				return
+			}
+		}
+	}
+
+	if port == "" {
+		var ok bool
+		port, ok = c.ports[scheme]
+		if !ok {
+			fail(len(pattern), ErrMissingPort)
+			return
 		}
+	}
+
+	// Hostnames are checked as in origins, apart from wildcards.
+	if host == "" {
+		fail(hostPos, ErrMissingHostname)
 		return
 	}
+	start := hostPos
+	if strings.HasPrefix(parts[1], "[") {
+		start++
+	}
+	for i := 0; i < len(host); i++ {
+		switch b := host[i]; {
+		case strings.HasPrefix(host[i:], c.wildcard):
+		case b <= ' ' || b == 0x7f || strings.IndexByte(`?#@[]%`, b) >= 0:
+			fail(start+i, fmt.Errorf("%w %q", ErrIllegalHostname, host))
+			return
+		}
+	}
 
-	var ok bool
-	port, ok = knownPorts[scheme]
-	if !ok {
-		err = errors.New("invalid origin: missing port")
+	if n := strings.Count(host, ".") + 1; n > c.maxLabels {
+		fail(hostPos, fmt.Errorf("%w (%d > %d)", ErrTooManyLabels, n, c.maxLabels))
 		return
 	}
 
 	return
 }
 
+// indexWildcard returns the index of the nth wildcard w in s, or -1 if
+// there are fewer. Escaped wildcards are skipped.
+func indexWildcard(s, w string, n int) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == escape[0] && strings.HasPrefix(s[i+1:], w):
+			i += len(w)
+		case strings.HasPrefix(s[i:], w):
+			if n--; n == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// countWildcards returns the number of wildcards w in s, escaped
+// wildcards excluded.
+func countWildcards(s, w string) int {
+	n := 0
+	for indexWildcard(s, w, n+1) >= 0 {
+		n++
+	}
+	return n
+}
+
+// hasWildcard returns true if s contains a wildcard that isn't
+// escaped.
+func (c *config) hasWildcard(s string) bool {
+	return indexWildcard(s, c.wildcard, 1) >= 0
+}
+
+// unescape returns s with its escaped wildcards replaced with literal
+// wildcard characters.
+func (c *config) unescape(s string) string {
+	if !strings.Contains(s, escape) {
+		return s
+	}
+	return strings.ReplaceAll(s, escape+c.wildcard, c.wildcard)
+}
+
+// cutWildcard slices s around its first wildcard that isn't escaped,
+// as strings.Cut does.
+func cutWildcard(s, w string) (before, after string, found bool) {
+	if i := indexWildcard(s, w, 1); i >= 0 {
+		return s[:i], s[i+len(w):], true
+	}
+	return s, "", false
+}
+
+// hostIndex returns the index of the hostname in pattern.
+func hostIndex(pattern string) int {
+	if i := strings.Index(pattern, "://"); i >= 0 {
+		return i + len("://")
+	}
+	return 0
+}
+
+// validScheme returns true if scheme is formatted as specified
+// in RFC 3986, section 3.1, once lowercased:
+//
+//	scheme = [a-z] *( [a-z] / DIGIT / "+" / "-" / "." )
+func validScheme(scheme string) bool {
+	if scheme == "" {
+		return false
+	}
+	for i, r := range scheme {
+		switch {
+		case 'a' <= r && r <= 'z':
+		case i > 0 && ('0' <= r && r <= '9' || r == '+' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validPort returns true if port is a number between 1 and 65535.
+func validPort(port string) bool {
+	n, err := strconv.ParseUint(port, 10, 16)
+	return err == nil && n > 0
+}
+
+// portRange is an inclusive range of port numbers.
+type portRange struct {
+	lo, hi uint64
+}
+
+// parsePorts parses a list of comma-separated port numbers, or ranges
+// of port numbers, such as "80,3000-3999".
+func parsePorts(spec string) ([]portRange, error) {
+	var ranges []portRange
+	for _, item := range strings.Split(spec, ",") {
+		lo, hi, isRange := strings.Cut(item, "-")
+		if !isRange {
+			hi = lo
+		}
+		if !validPort(lo) || !validPort(hi) {
+			return nil, fmt.Errorf("%w %q", ErrIllegalPort, item)
+		}
+
+		r := portRange{}
+		r.lo, _ = strconv.ParseUint(lo, 10, 16)
+		r.hi, _ = strconv.ParseUint(hi, 10, 16)
+		if r.lo > r.hi {
+			return nil, fmt.Errorf("%w range %q", ErrIllegalPort, item)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+// matchPort returns true if port is within any of the ranges, or if
+// ranges is nil.
+func matchPort(port string, ranges []portRange) bool {
+	if ranges == nil {
+		return port != ""
+	}
+
+	n, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return false
+	}
+	for _, r := range ranges {
+		if r.lo <= n && n <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
 // normalize readies a string for comparison.
+//
+// Unicode characters are converted to their canonical composed form
+// (NFC), so that equivalent sequences of code points compare equal.
 func normalize(s string) string {
 	s = strings.TrimSpace(s)
+	s = norm.NFC.String(s)
 	s = strings.ToLower(s)
 	return s
 }
 
+// normalizeHost readies a hostname for comparison, as normalize does.
+// Internationalized domain names are also converted to their ASCII
+// form (punycode), as sent by browsers.
+func normalizeHost(host string) string {
+	host = normalize(host)
+	if ascii, err := idna.Punycode.ToASCII(host); err == nil {
+		host = ascii
+	}
+	return host
+}
+
 // matchHostname matches a hostname against pattern.
-func matchHostname(origin, pattern string) (bool, error) {
-	a := strings.Split(normalize(pattern), ".")
-	b := strings.Split(normalize(origin), ".")
+//
+// Each wildcard in pattern matches exactly one label of the hostname,
+// except for a pattern consisting of a single wildcard, which matches
+// any hostname.
+//
+// A pattern starting with a dot, such as ".example.com", matches the
+// hostname that follows the dot as well as any of its subdomains, at
+// any depth. A pattern starting with a double wildcard, such as
+// "**.example.com", only matches the subdomains. The depth of the
+// subdomains matched is limited if the configuration sets a limit.
+//
+// IP addresses are only matched by an identical address, or by a
+// network in CIDR notation containing them, as the labels of a pattern
+// such as "*.example.com" could otherwise match the octets of an IPv4
+// address.
+//
+// Hostnames with more labels than allowed by the configuration are
+// never a match.
+func matchHostname(origin string, pattern *hostname, c *config) (bool, error) {
+	if pattern.any {
+		return true, nil
+	}
+
+	origin = normalizeHost(origin)
+	if addr, err := netip.ParseAddr(origin); err == nil {
+		addr = addr.Unmap()
+		if pattern.prefix.IsValid() {
+			return pattern.prefix.Contains(addr), nil
+		}
+		return pattern.addr.IsValid() && pattern.addr.Unmap() == addr, nil
+	}
+	if pattern.addr.IsValid() || pattern.prefix.IsValid() {
+		return false, nil
+	}
+
+	if strings.Count(origin, ".")+1 > c.maxLabels {
+		return false, nil
+	}
 
-	for i := len(a) - 1; i >= 0; i-- {
-		if len(b) < i+1 {
+	a := pattern.labels
+	b := strings.Split(origin, ".")
+	if pattern.suffix || pattern.deep {
+		if len(b) < len(a) || (pattern.deep && len(b) == len(a)) {
 			return false, nil
 		}
-		if a[i] == wildcard || b[i] == wildcard {
-			continue
+		if c.maxDepth > 0 && len(b)-len(a) > c.maxDepth {
+			return false, nil
 		}
-		if a[i] != b[i] {
+		b = b[len(b)-len(a):]
+	} else if len(a) != len(b) {
+		return false, nil
+	}
+
+	for i := range a {
+		if !matchLabel(b[i], a[i], c) {
 			return false, nil
 		}
 	}
+
+	if pattern.registrable != "" {
+		domain, err := publicsuffix.EffectiveTLDPlusOne(origin)
+		return err == nil && domain == pattern.registrable, nil
+	}
 	return true, nil
 }
 
-func matchString(origin, pattern string) (bool, error) {
+// matchLabel returns true if the label of a hostname matches the label
+// of a hostname pattern, which may contain wildcards matching any
+// sequence of characters, such as "pr-*". Escaped wildcards, such as
+// in "a\*b", only match a literal wildcard character.
+func matchLabel(label, pattern string, c *config) bool {
+	switch {
+	case pattern == c.wildcard || (c.originWildcards && label == c.wildcard):
+		return true
+	case !c.hasWildcard(pattern):
+		return label == c.unescape(pattern)
+	}
+
+	prefix, rest, _ := cutWildcard(pattern, c.wildcard)
+	prefix = c.unescape(prefix)
+	if !strings.HasPrefix(label, prefix) {
+		return false
+	}
+	label = label[len(prefix):]
+
+	for {
+		part, more, found := cutWildcard(rest, c.wildcard)
+		part = c.unescape(part)
+		if !found {
+			return strings.HasSuffix(label, part)
+		}
+		i := strings.Index(label, part)
+		if i < 0 {
+			return false
+		}
+		label, rest = label[i+len(part):], more
+	}
+}
+
+func matchScheme(origin, pattern string, c *config) (bool, error) {
 	if origin == "" {
 		return false, nil
 	}
 
-	if pattern == wildcard {
+	if pattern == c.wildcard {
 		return true, nil
 	}
 
+	if c.webSockets {
+		origin, pattern = webSocketScheme(origin), webSocketScheme(pattern)
+	}
 	return strings.EqualFold(origin, pattern), nil
 }
 
+// webSocketScheme returns the WebSocket protocol equivalent to scheme,
+// if any, or scheme itself.
+func webSocketScheme(scheme string) string {
+	switch strings.ToLower(scheme) {
+	case "http":
+		return "ws"
+	case "https":
+		return "wss"
+	}
+	return scheme
+}
+
 // Match returns true if the scheme, hostname and port
 // of origin match the ones in the given pattern.
 //
@@ -143,48 +623,87 @@ func matchString(origin, pattern string) (bool, error) {
 //
 //	scheme://hostname:port
 //
+// IPv6 addresses must be enclosed in square brackets, as in
+// "http://[::1]:8080".
+//
+// In a pattern, the port may also be a list of comma-separated port
+// numbers or ranges of port numbers, such as
+// "https://example.com:8080,8443" or "http://localhost:3000-3999".
+//
+// In a pattern, the scheme may also be a set of comma-separated schemes
+// enclosed in braces, such as "{http,https}://example.com". When the
+// port is omitted, each scheme then implies its own standard port.
+//
+// In a pattern, the hostname may also be an IP network in CIDR
+// notation, such as "http://192.168.1.0/24:3000", matching origins
+// whose hostname is an IP address within that network.
+//
 // Pattern may contain a wildcard "*" in any of the three
 // components. For example, "https://*.example.com:*" will consider
 // any subdomain of "example.com" on any port number as a match,
 // provided that the scheme is HTTPS.
 //
+// Internationalized domain names are compared in their ASCII form
+// (punycode), so that "https://bücher.example" and
+// "https://xn--bcher-kva.example" are a match.
+//
 // The port number may be omitted in either the origin or pattern
 // when the scheme has a known standard port number. For example,
 // "https://example.com" and "https://example.com:443" are a match.
 //
+// Each wildcard in the hostname matches exactly one label, so that
+// "https://*.example.com" matches "https://sub.example.com", but
+// neither "https://example.com" nor "https://a.b.example.com". To match
+// a domain and all of its subdomains, at any depth, the hostname can
+// instead start with a dot, as in "https://.example.com". To match the
+// subdomains only, at any depth, it can start with a double wildcard,
+// as in "https://**.example.com".
+//
+// A wildcard can also appear within a label, alongside other
+// characters, to match any sequence of characters within that label.
+// For example, "https://pr-*.preview.example.com" matches
+// "https://pr-42.preview.example.com", but not
+// "https://preview.example.com". A wildcard preceded by a backslash,
+// as in "https://a\*b.example.com", is matched literally instead.
+// Elsewhere, a wildcard must make up the whole scheme or port.
+//
 // The special pattern value "*" is equivalent to "*://*:*", and
-// matches with any non-empty and valid origin.
+// matches with any non-empty and valid origin, except for the opaque
+// origin "null". The latter is only matched by the pattern "null",
+// which must be allowed with the [AllowOpaque] option.
+//
+// The special pattern value [Loopback] matches the origins served on
+// the loopback interface, such as "http://localhost:3000",
+// "https://app.localhost" or "http://127.0.0.1:8080".
+//
+// An [*ErrInvalidOrigin] is returned if origin is malformed, and an
+// [*ErrInvalidPattern] if pattern is.
 func Match(origin, pattern string) (bool, error) {
-	os, oh, op, err := Split(origin)
-	if err != nil {
-		return false, err
-	}
-
-	if pattern == "" {
-		return false, errors.New("pattern cannot be an empty string")
-	}
-	if pattern == wildcard || pattern == anyValue {
-		return true, nil
-	}
+	return match(origin, pattern, defaultConfig)
+}
 
-	ps, ph, pp, err := splitPattern(pattern)
+// MatchWith is like [Match], but parses and matches pattern according
+// to the given options.
+func MatchWith(origin, pattern string, opts ...Option) (bool, error) {
+	c, err := newConfig(opts)
 	if err != nil {
 		return false, err
 	}
+	return match(origin, pattern, c)
+}
 
-	if ok, err := matchString(os, ps); !ok || err != nil {
-		return false, err
-	}
-
-	if ok, err := matchHostname(oh, ph); !ok || err != nil {
-		return false, err
-	}
-
-	if ok, err := matchString(op, pp); !ok || err != nil {
+func match(origin, pattern string, c *config) (bool, error) {
+	p, err := compile(pattern, c)
+	if err != nil {
 		return false, err
 	}
+	return p.allows(origin)
+}
 
-	return true, nil
+// IsWildcardPattern returns true if pattern matches any valid origin,
+// that is if pattern is either "*" or "*://*:*".
+func IsWildcardPattern(pattern string) bool {
+	return defaultConfig.isAny(pattern)
 }
 
 // Patterns holds a list of trusted origins or patterns against
@@ -192,37 +711,405 @@ func Match(origin, pattern string) (bool, error) {
 //
 // Valid values are well-formed URLs, or patterns formatted as
 // specified in the [Match] function.
+//
+// Patterns prefixed with an exclamation mark, such as
+// "!https://legacy.example.com", are negated: origins matching them
+// are denied, regardless of the other patterns in the list.
 type Patterns []string
 
 // Match returns true if any of the patterns in p matches
 // with origin.
 func (p Patterns) Match(origin string) (bool, error) {
+	return p.match(origin, defaultConfig)
+}
+
+// MatchWith is like [Patterns.Match], but parses and matches the
+// patterns in p according to the given options.
+func (p Patterns) MatchWith(origin string, opts ...Option) (bool, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return false, err
+	}
+	return p.match(origin, c)
+}
+
+func (p Patterns) match(origin string, c *config) (bool, error) {
+	i, err := p.matchIndex(origin, c)
+	if i < 0 || err != nil {
+		c.report(nil, origin, "", false, err)
+		return false, err
+	}
+	c.report(nil, origin, p[i], true, nil)
+	return true, nil
+}
+
+// MatchIndex returns the index of the first pattern in p that matches
+// with origin, or -1 if there is none.
+func (p Patterns) MatchIndex(origin string) (int, error) {
+	return p.matchIndex(origin, defaultConfig)
+}
+
+// MatchPattern returns the first pattern in p that matches with
+// origin, such as for audit logs, or an empty string if there is none.
+func (p Patterns) MatchPattern(origin string) (string, error) {
+	pattern, _, err := p.matchPattern(origin)
+	return pattern, err
+}
+
+// MatchBest returns the most specific of the patterns in p that match
+// with origin, as ranked by [Pattern.Specificity], or an empty string
+// if there is none, or if origin matches any of the negated patterns.
+// Of equally specific patterns, the first one is returned.
+//
+// It is meant for lists whose patterns are associated with different
+// settings, where the pattern describing an origin most precisely, such
+// as "https://admin.example.com" rather than "https://*.example.com",
+// determines the settings that apply.
+func (p Patterns) MatchBest(origin string) (string, error) {
 	if origin == "" {
-		return false, nil
+		return "", nil
+	}
+
+	best, score := -1, -1
+	for i, item := range p {
+		compiled, err := compile(item, defaultConfig)
+		if err != nil {
+			return "", err
+		}
+		ok, err := compiled.match(origin)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case !ok:
+		case compiled.deny:
+			return "", nil
+		case compiled.Specificity() > score:
+			best, score = i, compiled.Specificity()
+		}
+	}
+	if best < 0 {
+		return "", nil
+	}
+	return p[best], nil
+}
+
+func (p Patterns) matchIndex(origin string, c *config) (int, error) {
+	if origin == "" {
+		return -1, nil
+	}
+
+	// Negated patterns are evaluated first.
+	for _, deny := range []bool{true, false} {
+		for i, item := range p {
+			if strings.HasPrefix(item, negation) != deny {
+				continue
+			}
+
+			compiled, err := compile(item, c)
+			if err != nil {
+				return -1, err
+			}
+			ok, err := compiled.match(origin)
+			if err != nil {
+				return -1, err
+			}
+			if ok && deny {
+				return -1, nil
+			}
+			if ok {
+				return i, nil
+			}
+		}
+	}
+	return -1, nil
+}
+
+// MatchAllPatterns returns every pattern in p that matches with
+// origin, in order. Negated patterns are included if origin matches
+// the pattern following their exclamation mark.
+//
+// Unlike [Patterns.MatchIndex], all the patterns are evaluated, which
+// helps revealing redundant or overlapping patterns.
+func (p Patterns) MatchAllPatterns(origin string) (matches []string, err error) {
+	if origin == "" {
+		return nil, nil
 	}
 
 	for _, item := range p {
-		ok, err := Match(origin, item)
+		compiled, err := compile(item, defaultConfig)
 		if err != nil {
-			return false, err
+			return nil, err
+		}
+		ok, err := compiled.match(origin)
+		if err != nil {
+			return nil, err
 		}
 		if ok {
-			return true, nil
+			matches = append(matches, item)
 		}
 	}
-	return false, nil
+	return matches, nil
+}
+
+// MatchAll matches each of the origins against the patterns in p.
+//
+// The outcome for each origin is reported in results, at the same
+// index, and allMatched is true if every origin is a match. An empty
+// list of origins is considered to be entirely matched. The patterns
+// are compiled once, and an error is returned if any of them is invalid.
+func (p Patterns) MatchAll(origins []string) (allMatched bool, results []bool, err error) {
+	compiled, err := compilePatterns(p, defaultConfig)
+	if err != nil {
+		return false, nil, err
+	}
+
+	results = make([]bool, len(origins))
+	allMatched = true
+	for i, origin := range origins {
+		j, err := matchFirst(compiled, origin)
+		if err != nil {
+			return false, nil, err
+		}
+		results[i] = j >= 0
+		allMatched = allMatched && results[i]
+	}
+	return allMatched, results, nil
+}
+
+// Append validates pattern and returns a copy of p with pattern
+// appended to it. The copy is returned unchanged if p already holds
+// an equivalent pattern.
+func (p Patterns) Append(pattern string) (Patterns, error) {
+	if err := validatePattern(pattern, defaultConfig); err != nil {
+		return nil, err
+	}
+
+	q := make(Patterns, len(p), len(p)+1)
+	copy(q, p)
+	for _, item := range p {
+		if normalize(item) == normalize(pattern) {
+			return q, nil
+		}
+	}
+	return append(q, pattern), nil
+}
+
+// Without returns a copy of p from which the patterns equivalent to
+// pattern have been removed.
+func (p Patterns) Without(pattern string) Patterns {
+	q := make(Patterns, 0, len(p))
+	for _, item := range p {
+		if normalize(item) != normalize(pattern) {
+			q = append(q, item)
+		}
+	}
+	return q
+}
+
+// Diff returns the patterns that are in new but not in old (added),
+// and the ones that are in old but not in new (removed).
+//
+// Patterns are compared once canonicalized: duplicates are collapsed,
+// and so are patterns that only differ by case or by the mention of
+// the standard port of their scheme. The patterns returned are in
+// their canonical form.
+func Diff(old, new Patterns) (added, removed Patterns) {
+	a, b := old.canonical(), new.canonical()
+	return b.subtract(a), a.subtract(b)
+}
+
+// Compact returns the patterns in p in their canonical form, sorted and
+// without duplicates, so that lists of equivalent patterns are compacted
+// into identical lists. Patterns are canonicalized with a lowercase
+// scheme and hostname, internationalized domain names in their ASCII
+// form (punycode), and without port if it is the standard one for the
+// scheme. For example, "HTTPS://Bücher.example:443" becomes
+// "https://xn--bcher-kva.example".
+//
+// The order of the patterns doesn't change the origins matched by the
+// list, only which of them [Patterns.MatchPattern] reports. An error is
+// returned if any of the patterns is invalid.
+func (p Patterns) Compact() (Patterns, error) {
+	seen := make(map[string]bool, len(p))
+	q := make(Patterns, 0, len(p))
+	for i, item := range p {
+		s, err := canonicalPattern(item, defaultConfig)
+		if err != nil {
+			return nil, fmt.Errorf("pattern #%d (%q): %w", i, item, err)
+		}
+		if !seen[s] {
+			seen[s] = true
+			q = append(q, s)
+		}
+	}
+	sort.Strings(q)
+	return q, nil
+}
+
+// canonical returns the canonical form of the patterns in p, without
+// duplicates. Invalid patterns are only normalized.
+func (p Patterns) canonical() Patterns {
+	seen := make(map[string]bool, len(p))
+	q := make(Patterns, 0, len(p))
+	for _, item := range p {
+		s, err := canonicalPattern(item, defaultConfig)
+		if err != nil {
+			s = normalize(item)
+		}
+		if !seen[s] {
+			seen[s] = true
+			q = append(q, s)
+		}
+	}
+	return q
+}
+
+// subtract returns the patterns in p that are not in q.
+func (p Patterns) subtract(q Patterns) Patterns {
+	set := make(map[string]bool, len(q))
+	for _, item := range q {
+		set[item] = true
+	}
+
+	var r Patterns
+	for _, item := range p {
+		if !set[item] {
+			r = append(r, item)
+		}
+	}
+	return r
+}
+
+// canonicalPattern returns pattern with a lowercase scheme and
+// hostname, and without port if it is the standard one for the
+// scheme. Patterns matching any origin are returned as a single
+// wildcard.
+func canonicalPattern(pattern string, c *config) (string, error) {
+	if rest, ok := strings.CutPrefix(pattern, negation); ok {
+		s, err := canonicalPattern(rest, c)
+		return negation + s, err
+	}
+	if pattern == opaque || pattern == Loopback {
+		_, err := compile(pattern, c)
+		return pattern, err
+	}
+	if strings.HasPrefix(pattern, "{") {
+		return canonicalSchemeSet(pattern, c)
+	}
+
+	scheme, host, port, err := splitPattern(pattern, c)
+	if err != nil {
+		return "", err
+	}
+	if c.isAny(pattern) {
+		return c.wildcard, nil
+	}
+
+	host = normalizeHost(host)
+	if prefix, err := netip.ParsePrefix(host); err == nil {
+		host = prefix.Masked().String()
+	}
+	if port == c.ports[scheme] {
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+		return scheme + "://" + host, nil
+	}
+	return scheme + "://" + net.JoinHostPort(host, port), nil
+}
+
+// validatePattern returns an error if pattern is not a valid pattern.
+func validatePattern(pattern string, c *config) error {
+	_, err := compile(pattern, c)
+	return err
 }
 
 // Get returns the value of the origin header in r.
 //
-// An empty string is returned if the value in the header is "null",
-// indicating an [opaque origin].
+// The value "null", indicating an [opaque origin], is returned as is.
+// It is only matched by the pattern "null", allowed with the
+// [AllowOpaque] option.
 //
 // [opaque origin]: https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Origin#directives
 func Get(r *http.Request) string {
 	str := r.Header.Get("Origin")
-	if strings.EqualFold(str, "null") {
-		str = ""
+	if strings.EqualFold(str, opaque) {
+		str = opaque
 	}
 	return str
 }
+
+// GetStrict is like [Get], but returns an [*ErrInvalidOrigin] if r has
+// more than one origin header, with [ErrMultipleOrigins] as reason, or
+// if its value is not a valid origin, instead of using the first value
+// as is. Duplicate origin headers, which browsers never send, may be
+// smuggled by a client or an intermediary to confuse the checks made.
+//
+// An empty string and no error are returned if r has no origin header.
+func GetStrict(r *http.Request) (string, error) {
+	values := r.Header.Values("Origin")
+	switch {
+	case len(values) == 0:
+		return "", nil
+	case len(values) > 1:
+		return "", &ErrInvalidOrigin{Origin: strings.Join(values, ", "), Reason: ErrMultipleOrigins}
+	}
+
+	origin := Get(r)
+	if origin != opaque {
+		if _, _, _, _, err := split(origin, defaultConfig); err != nil {
+			return "", err
+		}
+	}
+	return origin, nil
+}
+
+// GetWithRefererFallback is like [Get], but derives the origin from
+// the referer header of r when the origin header is missing, as can be
+// the case with older clients or same-origin requests.
+//
+// The origin of the referer is its scheme, hostname and port, such as
+// "https://example.com" for "https://example.com/page?q=1". An empty
+// string is returned if neither header holds a usable value.
+//
+// Note that clients may omit or trim the referer header, depending on
+// their referrer policy.
+func GetWithRefererFallback(r *http.Request) string {
+	if str := Get(r); str != "" {
+		return str
+	}
+
+	u, err := url.Parse(r.Referer())
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// canonicalSchemeSet returns the canonical form of a pattern starting
+// with a set of schemes: the schemes are sorted and deduplicated, and
+// the rest of the pattern is canonicalized as for the first scheme, if
+// that doesn't depend on the scheme.
+func canonicalSchemeSet(pattern string, c *config) (string, error) {
+	schemes, rest, err := cutSchemeSet(pattern)
+	if err != nil {
+		return "", err
+	}
+
+	sort.Strings(schemes)
+	schemes = slices.Compact(schemes)
+
+	var suffixes []string
+	for _, scheme := range schemes {
+		s, err := canonicalPattern(scheme+"://"+rest, c)
+		if err != nil {
+			return "", err
+		}
+		suffixes = append(suffixes, strings.TrimPrefix(s, scheme))
+	}
+	if len(slices.Compact(suffixes)) > 1 {
+		return "{" + strings.Join(schemes, ",") + "}://" + rest, nil
+	}
+	return "{" + strings.Join(schemes, ",") + "}" + suffixes[0], nil
+}
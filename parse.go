@@ -0,0 +1,179 @@
+package origin
+
+import (
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Origin is the parsed representation of an origin.
+type Origin struct {
+	Scheme string // lowercase scheme, such as "https"
+	Host   string // lowercase hostname or IP address, without brackets
+	Port   string // port number, explicit or inferred from the scheme
+}
+
+// ParseOrigin parses origin into an [Origin], as [Split] does. The
+// hostname is normalized to lowercase.
+func ParseOrigin(origin string) (Origin, error) {
+	scheme, host, port, err := Split(origin)
+	if err != nil {
+		return Origin{}, err
+	}
+	return Origin{
+		Scheme: scheme,
+		Host:   normalizeHost(host),
+		Port:   port,
+	}, nil
+}
+
+// ParseStrict is like [ParseOrigin], but only accepts origins
+// serialized as browsers do in the origin header, according to
+// [RFC 6454]: the origin must already be in the form returned by
+// [Canonicalize]. For example, "https://example.com" is accepted, while
+// "https://Example.com" and "https://example.com:443" are rejected with
+// an [*ErrInvalidOrigin] whose reason is [ErrNotCanonical].
+//
+// Like [ParseOrigin], it rejects origins with user information, a
+// path, a query or a fragment, or without a hostname.
+//
+// [RFC 6454]: https://www.rfc-editor.org/rfc/rfc6454#section-6.2
+func ParseStrict(origin string) (Origin, error) {
+	o, err := ParseOrigin(origin)
+	if err != nil {
+		return Origin{}, err
+	}
+	if s, _ := Canonicalize(origin); s != origin || !IsValid(origin) {
+		return Origin{}, &ErrInvalidOrigin{Origin: origin, Reason: ErrNotCanonical}
+	}
+	return o, nil
+}
+
+// Canonicalize returns the serialization of origin as performed by
+// browsers: scheme and hostname in lowercase, internationalized domain
+// names in their ASCII form (punycode), IPv6 addresses in their
+// shortest form, and the port without leading zeros, or omitted if it
+// is the standard one for the scheme. For example,
+// "HTTPS://Bücher.Example:443" is canonicalized as
+// "https://xn--bcher-kva.example".
+//
+// The opaque origin "null" is returned as is. An error is returned if
+// origin is not a valid origin.
+func Canonicalize(origin string) (string, error) {
+	if origin == opaque {
+		return origin, nil
+	}
+
+	o, err := ParseOrigin(origin)
+	if err != nil {
+		return "", err
+	}
+	if addr, err := netip.ParseAddr(o.Host); err == nil {
+		o.Host = addr.String()
+	}
+	if n, err := strconv.ParseUint(o.Port, 10, 16); err == nil {
+		o.Port = strconv.FormatUint(n, 10)
+	}
+	return o.String(), nil
+}
+
+// Equal returns true if a and b are the same origin, as defined in
+// [RFC 6454]: same scheme, hostname and port, once canonicalized with
+// [Canonicalize]. For example, "https://Example.com" and
+// "https://example.com:443" are the same origin.
+//
+// The opaque origin "null" is never the same origin as any other,
+// itself included. An error is returned if either a or b is not a
+// valid origin.
+//
+// [RFC 6454]: https://www.rfc-editor.org/rfc/rfc6454#section-5
+func Equal(a, b string) (bool, error) {
+	ca, err := Canonicalize(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := Canonicalize(b)
+	if err != nil {
+		return false, err
+	}
+	return ca == cb && ca != opaque, nil
+}
+
+// String returns the serialization of o, as sent by browsers in the
+// origin header: the port is omitted if it is the standard one for the
+// scheme.
+func (o Origin) String() string {
+	if o.Port == knownPorts[o.Scheme] {
+		if strings.Contains(o.Host, ":") {
+			return o.Scheme + "://[" + o.Host + "]"
+		}
+		return o.Scheme + "://" + o.Host
+	}
+	return o.Scheme + "://" + net.JoinHostPort(o.Host, o.Port)
+}
+
+// Equal returns true if o and other have the same scheme, host and
+// port. Scheme and host are compared case-insensitively.
+func (o Origin) Equal(other Origin) bool {
+	return strings.EqualFold(o.Scheme, other.Scheme) &&
+		normalizeHost(o.Host) == normalizeHost(other.Host) &&
+		o.Port == other.Port
+}
+
+// Compare returns an integer comparing o and other, ordered by scheme,
+// host and port. The result is 0 if o and other are equal, -1 if o is
+// ordered first, and +1 otherwise.
+func (o Origin) Compare(other Origin) int {
+	if c := strings.Compare(strings.ToLower(o.Scheme), strings.ToLower(other.Scheme)); c != 0 {
+		return c
+	}
+	if c := strings.Compare(normalizeHost(o.Host), normalizeHost(other.Host)); c != 0 {
+		return c
+	}
+	return strings.Compare(o.Port, other.Port)
+}
+
+// IsIP returns true if the host of o is an IP address, rather than a
+// domain name.
+func (o Origin) IsIP() bool {
+	_, err := netip.ParseAddr(o.Host)
+	return err == nil
+}
+
+// IsLoopback returns true if o is served on the loopback interface: its
+// host is either "localhost", one of its subdomains, or a loopback IP
+// address such as 127.0.0.1 or ::1. These are the origins matched by the
+// [Loopback] pattern, with any scheme.
+func (o Origin) IsLoopback() bool {
+	if addr, err := netip.ParseAddr(o.Host); err == nil {
+		return addr.Unmap().IsLoopback()
+	}
+	host := strings.ToLower(o.Host)
+	return host == "localhost" || strings.HasSuffix(host, ".localhost")
+}
+
+// IsPrivate returns true if the host of o is an IP address in a private
+// network, according to [RFC 1918] for IPv4 addresses, and to
+// [RFC 4193] (unique local addresses) for IPv6 addresses. Domain names
+// are not resolved, so that IsPrivate returns false for them.
+//
+// [RFC 1918]: https://www.rfc-editor.org/rfc/rfc1918
+// [RFC 4193]: https://www.rfc-editor.org/rfc/rfc4193
+func (o Origin) IsPrivate() bool {
+	addr, err := netip.ParseAddr(o.Host)
+	return err == nil && addr.Unmap().IsPrivate()
+}
+
+// IsSecure returns true if the scheme of o is a secure one, "https" or
+// "wss". Note that browsers also consider documents served from a
+// loopback origin (see [Origin.IsLoopback]) as secure contexts.
+func (o Origin) IsSecure() bool {
+	return strings.EqualFold(o.Scheme, "https") || strings.EqualFold(o.Scheme, "wss")
+}
+
+// Match returns true if o matches pattern, as specified in the [Match]
+// function.
+func (o Origin) Match(pattern string) (bool, error) {
+	return Match(o.String(), pattern)
+}
@@ -0,0 +1,184 @@
+package origin
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseOrigin(t *testing.T) {
+	type testCase struct {
+		Origin string
+		Want   Origin
+		String string
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", Origin{"https", "example.com", "443"}, "https://example.com"},
+		{"https://Example.COM:443", Origin{"https", "example.com", "443"}, "https://example.com"},
+		{"http://example.com:8080", Origin{"http", "example.com", "8080"}, "http://example.com:8080"},
+		{"http://[::1]", Origin{"http", "::1", "80"}, "http://[::1]"},
+		{"http://[::1]:3000", Origin{"http", "::1", "3000"}, "http://[::1]:3000"},
+		{"custom://example.com:54232", Origin{"custom", "example.com", "54232"}, "custom://example.com:54232"},
+	}
+
+	for _, tc := range cases {
+		o, err := ParseOrigin(tc.Origin)
+		if err != nil {
+			t.Errorf("Origin: %s - Error: %v", tc.Origin, err)
+			continue
+		}
+		if o != tc.Want {
+			t.Errorf("Origin: %s - Wanted: %#v, Got: %#v", tc.Origin, tc.Want, o)
+		}
+		if s := o.String(); s != tc.String {
+			t.Errorf("Origin: %s - Wanted: %s, Got: %s", tc.Origin, tc.String, s)
+		}
+	}
+
+	if _, err := ParseOrigin("example.com"); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	var cases = map[string]error{
+		"https://example.com":           nil,
+		"http://localhost:3000":         nil,
+		"http://[::1]":                  nil,
+		"https://xn--bcher-kva.example": nil,
+		"https://Example.com":           ErrNotCanonical,
+		"HTTPS://example.com":           ErrNotCanonical,
+		"https://example.com:443":       ErrNotCanonical,
+		"https://bücher.example":        ErrNotCanonical,
+		"http://[::A]":                  ErrNotCanonical,
+		"https://ex ample.com":          ErrIllegalHostname,
+		"https://example.com/evil?x=1":  ErrIllegalHostname,
+		"https://user@example.com":      ErrIllegalHostname,
+		"https://example.com#top":       ErrIllegalHostname,
+		"https://":                      ErrMissingHostname,
+		"null":                          ErrMissingScheme,
+	}
+
+	for origin, want := range cases {
+		o, err := ParseStrict(origin)
+		if !errors.Is(err, want) || (err == nil) != (want == nil) {
+			t.Errorf("Origin: %q - Wanted: %v, Got: %v", origin, want, err)
+		}
+		if err == nil && o.String() != origin {
+			t.Errorf("Origin: %q - Got: %q", origin, o.String())
+		}
+	}
+}
+
+func TestOriginPredicates(t *testing.T) {
+	type testCase struct {
+		Origin                                string
+		IsIP, IsLoopback, IsPrivate, IsSecure bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", false, false, false, true},
+		{"http://localhost:3000", false, true, false, false},
+		{"http://app.localhost", false, true, false, false},
+		{"http://localhost.example.com", false, false, false, false},
+		{"http://127.0.0.1:8080", true, true, false, false},
+		{"https://[::1]", true, true, false, true},
+		{"http://[::ffff:127.0.0.1]", true, true, false, false},
+		{"http://10.1.2.3", true, false, true, false},
+		{"http://172.16.0.1", true, false, true, false},
+		{"http://192.168.1.42:3000", true, false, true, false},
+		{"http://[fd00::1]", true, false, true, false},
+		{"http://93.184.216.34", true, false, false, false},
+		{"wss://example.com", false, false, false, true},
+	}
+
+	for _, tc := range cases {
+		o, err := ParseOrigin(tc.Origin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o.IsIP() != tc.IsIP || o.IsLoopback() != tc.IsLoopback || o.IsPrivate() != tc.IsPrivate || o.IsSecure() != tc.IsSecure {
+			t.Errorf("Origin: %s - Wanted: %v %v %v %v, Got: %v %v %v %v", tc.Origin,
+				tc.IsIP, tc.IsLoopback, tc.IsPrivate, tc.IsSecure,
+				o.IsIP(), o.IsLoopback(), o.IsPrivate(), o.IsSecure())
+		}
+	}
+}
+
+func TestOriginCompare(t *testing.T) {
+	a := Origin{"https", "example.com", "443"}
+	b := Origin{"HTTPS", "Example.com", "443"}
+	c := Origin{"https", "example.com", "8443"}
+
+	if !a.Equal(b) || a.Compare(b) != 0 {
+		t.Errorf("%v and %v should be equal", a, b)
+	}
+	if a.Equal(c) || a.Compare(c) >= 0 || c.Compare(a) <= 0 {
+		t.Errorf("%v should be ordered before %v", a, c)
+	}
+
+	if ok, err := c.Match("https://*.com:*"); !ok || err != nil {
+		t.Errorf("Got: %v, %v", ok, err)
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		Result   string
+		HasError bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", "https://example.com", false},
+		{"HTTPS://Example.COM:443", "https://example.com", false},
+		{"http://example.com:8080/", "", true},
+		{"https://Bücher.Example", "https://xn--bcher-kva.example", false},
+		{"http://[0:0::0001]:80", "http://[::1]", false},
+		{"http://[::ffff:192.0.2.1]:3000", "http://[::ffff:192.0.2.1]:3000", false},
+		{"https://example.com:0443", "https://example.com", false},
+		{"https://example.com:08443", "https://example.com:8443", false},
+		{"null", "null", false},
+		{"example.com", "", true},
+	}
+
+	for _, tc := range cases {
+		got, err := Canonicalize(tc.Origin)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s - Error: %v", tc.Origin, err)
+		}
+		if got != tc.Result {
+			t.Errorf("Origin: %s - Wanted: %q, Got: %q", tc.Origin, tc.Result, got)
+		}
+	}
+}
+
+func TestEqual(t *testing.T) {
+	type testCase struct {
+		A, B     string
+		IsEqual  bool
+		HasError bool
+	}
+
+	var cases = []*testCase{
+		{"https://example.com", "https://example.com", true, false},
+		{"https://Example.com", "HTTPS://example.com:443", true, false},
+		{"http://[::1]", "http://[0::1]:80", true, false},
+		{"https://bücher.example", "https://xn--bcher-kva.example", true, false},
+		{"https://example.com", "http://example.com", false, false},
+		{"https://example.com", "https://example.com:8443", false, false},
+		{"https://example.com", "https://sub.example.com", false, false},
+		{"null", "null", false, false},
+		{"https://example.com", "example.com", false, true},
+	}
+
+	for _, tc := range cases {
+		isEqual, err := Equal(tc.A, tc.B)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("%s, %s - Error: %v", tc.A, tc.B, err)
+		}
+		if isEqual != tc.IsEqual {
+			t.Errorf("%s, %s - Wanted: %v, Got: %v", tc.A, tc.B, tc.IsEqual, isEqual)
+		}
+	}
+}
@@ -0,0 +1,589 @@
+package origin
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Pattern is a compiled pattern, ready to be matched against origins.
+//
+// Compiling a pattern once, rather than passing it to [Match] for every
+// origin, spares the cost of parsing it repeatedly.
+//
+// A Pattern is safe for concurrent use.
+type Pattern struct {
+	raw    string
+	scheme string
+	host   hostname
+	port   string
+	ports  []portRange    // nil if the port is a wildcard
+	re     *regexp.Regexp // set for patterns in the regular expression dialect
+	opaque bool           // whether the pattern is "null"
+	deny   bool           // whether the pattern is negated
+	alts   []*Pattern     // one pattern per scheme, for a set of schemes
+	c      *config
+
+	// exact and exactPort are the serializations of the only origin
+	// matched by a pattern without wildcards, with and without the port
+	// number when it is the standard one for the scheme.
+	exact, exactPort string
+}
+
+// hostname is the compiled hostname of a pattern.
+type hostname struct {
+	any    bool         // whether the hostname is a single wildcard
+	addr   netip.Addr   // the IP address, if the hostname is one
+	prefix netip.Prefix // the IP network, if the hostname is in CIDR notation
+	labels []string     // the normalized labels of the hostname
+	suffix bool         // whether the hostname starts with a dot
+	deep   bool         // whether the hostname starts with a double wildcard
+
+	// registrable is the registrable domain that matching hostnames must
+	// have, when matching by registrable domain.
+	registrable string
+}
+
+// Compile parses pattern, formatted as specified in the [Match]
+// function, according to the given options.
+func Compile(pattern string, opts ...Option) (*Pattern, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return compile(pattern, c)
+}
+
+// MustCompile is like [Compile], but panics if pattern cannot be
+// parsed. It simplifies the initialization of global variables holding
+// compiled patterns.
+func MustCompile(pattern string, opts ...Option) *Pattern {
+	p, err := Compile(pattern, opts...)
+	if err != nil {
+		panic("origin: Compile(" + strconv.Quote(pattern) + "): " + err.Error())
+	}
+	return p
+}
+
+// regexpPrefix introduces a pattern in the regular expression dialect,
+// when enabled with the [AllowRegexp] option.
+const regexpPrefix = "re:"
+
+// CompileRegexp compiles a pattern matching the origins that entirely
+// match the regular expression expr, written in the syntax accepted by
+// the [regexp] package.
+//
+// The expression is matched against the origin as sent by the client,
+// which must be valid. For example, `https://pr-\d+\.preview\.example\.com`
+// matches "https://pr-42.preview.example.com".
+func CompileRegexp(expr string, opts ...Option) (*Pattern, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return compileRegexp(expr, c)
+}
+
+func compileRegexp(expr string, c *config) (*Pattern, error) {
+	if expr == "" {
+		return nil, &ErrInvalidPattern{Pattern: regexpPrefix, Pos: len(regexpPrefix), Reason: ErrEmpty}
+	}
+
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return nil, &ErrInvalidPattern{Pattern: regexpPrefix + expr, Pos: -1, Reason: err}
+	}
+	return &Pattern{raw: regexpPrefix + expr, re: re, c: c}, nil
+}
+
+// negation is the prefix of a negated pattern.
+const negation = "!"
+
+// escape is the prefix of a wildcard character to be matched
+// literally, rather than as a wildcard.
+const escape = `\`
+
+func compile(pattern string, c *config) (*Pattern, error) {
+	if pattern == "" {
+		return nil, &ErrInvalidPattern{Pattern: pattern, Pos: 0, Reason: ErrEmpty}
+	}
+	if rest, ok := strings.CutPrefix(pattern, negation); ok {
+		p, err := compile(rest, c)
+		var perr *ErrInvalidPattern
+		if errors.As(err, &perr) {
+			// Report the position within the negated pattern.
+			if perr.Pos >= 0 {
+				perr.Pos += len(negation)
+			}
+			perr.Pattern = pattern
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.deny {
+			return nil, &ErrInvalidPattern{Pattern: pattern, Pos: len(negation), Reason: ErrDoubleNegation}
+		}
+		p.raw, p.deny = pattern, true
+		return p, nil
+	}
+	if pattern == opaque {
+		if !c.opaque {
+			return nil, &ErrInvalidPattern{Pattern: pattern, Pos: 0, Reason: ErrOpaqueOrigin}
+		}
+		return &Pattern{raw: pattern, opaque: true, c: c}, nil
+	}
+	if pattern == Loopback {
+		return compileLoopback(c)
+	}
+	if c.regexp && strings.HasPrefix(pattern, regexpPrefix) {
+		return compileRegexp(pattern[len(regexpPrefix):], c)
+	}
+	if strings.HasPrefix(pattern, "{") {
+		return compileSchemeSet(pattern, c)
+	}
+
+	scheme, host, port, err := splitPattern(pattern, c)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pattern{
+		raw:    pattern,
+		scheme: scheme,
+		port:   port,
+		c:      c,
+	}
+
+	if port != c.wildcard {
+		if p.ports, err = parsePorts(port); err != nil {
+			return nil, &ErrInvalidPattern{Pattern: pattern, Pos: -1, Reason: err}
+		}
+	}
+
+	host = normalizeHost(host)
+	if c.trailingDot && len(host) > 1 {
+		host = strings.TrimSuffix(host, ".")
+	}
+	switch addr, err := netip.ParseAddr(host); {
+	case host == c.wildcard:
+		p.host.any = true
+	case err == nil:
+		p.host.addr = addr
+	case strings.Contains(host, "/"):
+		prefix, err := netip.ParsePrefix(host)
+		if err != nil {
+			return nil, &ErrInvalidPattern{Pattern: pattern, Pos: hostIndex(pattern), Reason: err}
+		}
+		p.host.prefix = prefix.Masked()
+	default:
+		p.host.labels = strings.Split(host, ".")
+		switch {
+		case p.host.labels[0] == "" && len(p.host.labels) > 1:
+			p.host.labels = p.host.labels[1:]
+			p.host.suffix = true
+		case p.host.labels[0] == c.wildcard+c.wildcard && len(p.host.labels) > 1:
+			p.host.labels = p.host.labels[1:]
+			p.host.deep = true
+		}
+		if c.anchored && (p.host.suffix || p.host.deep) {
+			return nil, &ErrInvalidPattern{Pattern: pattern, Pos: hostIndex(pattern), Reason: ErrUnanchored}
+		}
+		for _, label := range p.host.labels {
+			if label == c.wildcard+c.wildcard {
+				// A double wildcard is only meaningful as the leftmost label.
+				return nil, &ErrInvalidPattern{Pattern: pattern, Pos: hostIndex(pattern), Reason: ErrStrayWildcard}
+			}
+		}
+
+		if c.registrable && !p.host.suffix && !p.host.deep && !c.hasWildcard(host) {
+			p.host.registrable, err = publicsuffix.EffectiveTLDPlusOne(c.unescape(host))
+			if err != nil {
+				return nil, &ErrInvalidPattern{Pattern: pattern, Pos: hostIndex(pattern), Reason: err}
+			}
+			p.host.suffix = true
+		}
+	}
+
+	if c.publicSuffixGuard && p.host.spansPublicSuffix(c) {
+		return nil, &ErrInvalidPattern{Pattern: pattern, Pos: hostIndex(pattern), Reason: fmt.Errorf("%w: %q", ErrPublicSuffix, host)}
+	}
+	if c.globalGuard && p.host.isGlobal(c) {
+		return nil, &ErrInvalidPattern{Pattern: pattern, Pos: hostIndex(pattern), Reason: fmt.Errorf("%w: %q", ErrGlobalWildcard, host)}
+	}
+
+	if p.isExact() {
+		port = strconv.FormatUint(p.ports[0].lo, 10)
+		p.exactPort = scheme + "://" + c.unescape(host) + ":" + port
+		p.exact = p.exactPort
+		if port == c.ports[scheme] {
+			p.exact = scheme + "://" + c.unescape(host)
+		}
+		// Only valid origins may match without being parsed.
+		if !IsValid(p.exactPort) {
+			p.exact, p.exactPort = "", ""
+		}
+	}
+	return p, nil
+}
+
+// isExact returns true if p matches a single origin, identified by its
+// domain name rather than by an IP address, and if origins are allowed
+// to omit their port number.
+func (p *Pattern) isExact() bool {
+	if p.c.explicitPort || p.c.webSockets || p.scheme == p.c.wildcard || p.host.labels == nil || p.host.suffix || p.host.deep {
+		return false
+	}
+	if len(p.ports) != 1 || p.ports[0].lo != p.ports[0].hi {
+		return false
+	}
+	for _, label := range p.host.labels {
+		if p.c.hasWildcard(label) {
+			return false
+		}
+	}
+	return true
+}
+
+// Loopback is a special pattern value matching the origins served on
+// the loopback interface, on any port, with either the "http" or the
+// "https" scheme: "localhost" and its subdomains, the IPv4 addresses
+// in 127.0.0.0/8, and the IPv6 address ::1.
+const Loopback = "loopback"
+
+// loopbackHosts are the hostname patterns the Loopback pattern is made
+// of.
+var loopbackHosts = []string{".localhost", "127.0.0.0/8", "[::1]"}
+
+// compileLoopback compiles the Loopback pattern into one pattern per
+// scheme and hostname.
+func compileLoopback(c *config) (*Pattern, error) {
+	// Options restricting the hostnames of patterns are meant for the
+	// ones written by users.
+	lc := *c
+	lc.maxWildcards, lc.maxDepth = defaultMaxWildcards, 0
+	lc.publicSuffixGuard, lc.globalGuard = false, false
+	lc.registrable, lc.anchored = false, false
+
+	p := &Pattern{raw: Loopback, c: c}
+	for _, scheme := range []string{"http", "https"} {
+		for _, host := range loopbackHosts {
+			alt, err := compile(scheme+"://"+host+":"+c.wildcard, &lc)
+			if err != nil {
+				return nil, err
+			}
+			p.alts = append(p.alts, alt)
+		}
+	}
+	return p, nil
+}
+
+// compileSchemeSet compiles a pattern starting with a set of schemes,
+// such as "{http,https}://example.com", into one pattern per scheme.
+func compileSchemeSet(pattern string, c *config) (*Pattern, error) {
+	schemes, rest, err := cutSchemeSet(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Pattern{raw: pattern, c: c}
+	for _, scheme := range schemes {
+		alt, err := compile(scheme+"://"+rest, c)
+		var perr *ErrInvalidPattern
+		if errors.As(err, &perr) {
+			// Report the position within the set of schemes.
+			if perr.Pos >= len(scheme) {
+				perr.Pos += len(pattern) - len(rest) - len(scheme) - len("://")
+			}
+			perr.Pattern = pattern
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.alts = append(p.alts, alt)
+	}
+	return p, nil
+}
+
+// cutSchemeSet splits a pattern starting with a set of schemes into
+// the lowercase schemes of the set, and the rest of the pattern
+// following "://".
+func cutSchemeSet(pattern string) (schemes []string, rest string, err error) {
+	end := strings.Index(pattern, "}://")
+	if !strings.HasPrefix(pattern, "{") || end < 0 {
+		return nil, "", &ErrInvalidPattern{Pattern: pattern, Pos: 0, Reason: fmt.Errorf("%w: unterminated set", ErrIllegalScheme)}
+	}
+
+	pos := 1
+	for _, scheme := range strings.Split(pattern[1:end], ",") {
+		if s := strings.ToLower(scheme); validScheme(s) {
+			schemes = append(schemes, s)
+		} else {
+			return nil, "", &ErrInvalidPattern{Pattern: pattern, Pos: pos, Reason: fmt.Errorf("%w %q", ErrIllegalScheme, scheme)}
+		}
+		pos += len(scheme) + 1
+	}
+	return schemes, pattern[end+len("}://"):], nil
+}
+
+// spansPublicSuffix returns true if h matches hostnames under more than
+// one registrable domain, such as "*.com", "*.co.uk" or ".github.io".
+func (h *hostname) spansPublicSuffix(c *config) bool {
+	if h.any {
+		return true
+	}
+	fixed, ok := h.fixedLabels(c)
+	if !ok {
+		return false
+	}
+	if fixed == 0 {
+		return true
+	}
+
+	domain := strings.Join(h.labels[len(h.labels)-fixed:], ".")
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+// isGlobal returns true if h matches any hostname, or hostnames under
+// more than one domain of a top-level domain.
+func (h *hostname) isGlobal(c *config) bool {
+	if h.any {
+		return true
+	}
+	fixed, ok := h.fixedLabels(c)
+	return ok && fixed < 2
+}
+
+// fixedLabels returns the number of labels of h on the right of its
+// last wildcard, and false if h matches a single hostname, or is not a
+// domain name.
+func (h *hostname) fixedLabels(c *config) (int, bool) {
+	if h.labels == nil {
+		return 0, false
+	}
+
+	fixed := 0
+	for i := len(h.labels) - 1; i >= 0 && !c.hasWildcard(h.labels[i]); i-- {
+		fixed++
+	}
+	if fixed == len(h.labels) && !h.suffix && !h.deep {
+		return 0, false
+	}
+	return fixed, true
+}
+
+// String returns the source text used to compile the pattern.
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// MarshalText implements the [encoding.TextMarshaler] interface,
+// returning the pattern as written.
+func (p *Pattern) MarshalText() ([]byte, error) {
+	return []byte(p.raw), nil
+}
+
+// UnmarshalText implements the [encoding.TextUnmarshaler] interface,
+// compiling text with the default options, so that decoding a pattern,
+// such as from a JSON document, fails if it is invalid. Patterns that
+// need options, such as "null" with [AllowOpaque], can't be decoded
+// this way, and must be compiled with [Compile] instead.
+func (p *Pattern) UnmarshalText(text []byte) error {
+	compiled, err := compile(string(text), defaultConfig)
+	if err != nil {
+		return fmt.Errorf("%q: %w", text, err)
+	}
+	*p = *compiled
+	return nil
+}
+
+// Specificity returns a score ranking p among other patterns by how
+// precisely it describes the origins it matches, such as to choose
+// among several patterns matching an origin. Only the relative order of
+// scores is meaningful.
+//
+// The hostname weighs the most, followed by the port and the scheme. A
+// pattern matching a single origin ranks first, followed by patterns
+// with a wildcard port, then with wildcards in the hostname, from the
+// ones with the most literal labels, and finally the patterns matching
+// any hostname. For example, from the most to the least specific:
+//
+//	https://example.com
+//	https://example.com:*
+//	https://*.example.com
+//	https://.example.com
+//	*
+func (p *Pattern) Specificity() int {
+	switch {
+	case p.opaque:
+		return specificHost * 9
+	case p.re != nil:
+		return 1
+	case p.alts != nil:
+		score := -1
+		for _, alt := range p.alts {
+			if s := alt.Specificity(); score < 0 || s < score {
+				score = s
+			}
+		}
+		if len(p.alts) > 1 {
+			// A set of schemes is less specific than any of them.
+			score--
+		}
+		return score
+	}
+
+	host := 0
+	switch h := p.host; {
+	case h.any:
+	case h.addr.IsValid():
+		host = specificHost
+	case h.prefix.IsValid():
+		host = 1 + h.prefix.Bits()
+	case !h.suffix && !h.deep && !p.c.hasWildcard(strings.Join(h.labels, ".")):
+		host = specificHost
+	default:
+		host = 1
+		for _, label := range h.labels {
+			switch {
+			case label == p.c.wildcard:
+				host++
+			case p.c.hasWildcard(label):
+				host += 2
+			default:
+				host += 3
+			}
+		}
+		if !h.suffix && !h.deep {
+			host++
+		}
+	}
+
+	port := 0
+	switch {
+	case len(p.ports) == 1 && p.ports[0].lo == p.ports[0].hi:
+		port = 2
+	case p.ports != nil:
+		port = 1
+	}
+
+	scheme := 0
+	if p.scheme != p.c.wildcard {
+		scheme = 2
+	}
+	return host*9 + port*3 + scheme
+}
+
+// specificHost is the specificity score of a hostname matching a single
+// hostname, above the score of any hostname pattern with wildcards.
+const specificHost = 1 << 10
+
+// Matches returns true if origin is a valid origin matching p.
+//
+// For a negated pattern, such as "!https://example.com", Matches
+// returns true if origin is a valid origin that doesn't match the
+// pattern following the exclamation mark.
+func (p *Pattern) Matches(origin string) bool {
+	ok, err := p.allows(origin)
+	return ok && err == nil
+}
+
+// MatchOrigin implements the [Matcher] interface. Like Matches, it
+// returns true if origin is a valid origin matching p, but also returns
+// an error if origin is not a valid origin. An empty origin is never a
+// match.
+func (p *Pattern) MatchOrigin(origin string) (bool, error) {
+	if origin == "" {
+		return false, nil
+	}
+	return p.allows(origin)
+}
+
+// Negated returns true if p is a negated pattern.
+func (p *Pattern) Negated() bool {
+	return p.deny
+}
+
+// allows returns true if origin matches p, or doesn't match p if p is
+// negated.
+func (p *Pattern) allows(origin string) (bool, error) {
+	ok, err := p.match(origin)
+	if err != nil {
+		return false, err
+	}
+	return ok != p.deny, nil
+}
+
+// match returns true if origin matches p, regardless of whether p is
+// negated.
+func (p *Pattern) match(origin string) (bool, error) {
+	if origin == opaque {
+		return p.opaque, nil
+	}
+	if p.alts != nil {
+		for _, alt := range p.alts {
+			if ok, err := alt.match(origin); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	// Origins sent by browsers are canonical, and can be compared as is
+	// with the only origin matched by a pattern without wildcards. A
+	// canonical origin with a port number that doesn't start with a zero
+	// is necessarily a different origin if it isn't equal.
+	if p.exact != "" {
+		if origin == p.exact || origin == p.exactPort {
+			return true, nil
+		}
+		if IsValid(origin) && !strings.Contains(origin, ":0") {
+			return false, nil
+		}
+	}
+
+	os, oh, op, _, err := split(origin, p.c)
+	if err != nil {
+		return false, err
+	}
+	return p.matchSplit(origin, os, oh, op)
+}
+
+// matchSplit is like match, given the scheme, hostname and port
+// already split from origin.
+func (p *Pattern) matchSplit(origin, os, oh, op string) (bool, error) {
+	if origin == opaque || p.opaque {
+		return origin == opaque && p.opaque, nil
+	}
+	if p.alts != nil {
+		for _, alt := range p.alts {
+			if ok, err := alt.matchSplit(origin, os, oh, op); ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	if p.re != nil {
+		return p.re.MatchString(origin), nil
+	}
+
+	if ok, err := matchScheme(os, p.scheme, p.c); !ok || err != nil {
+		return false, err
+	}
+
+	if ok, err := matchHostname(oh, &p.host, p.c); !ok || err != nil {
+		return false, err
+	}
+
+	if !matchPort(op, p.ports) {
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -0,0 +1,319 @@
+package origin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	p, err := Compile("https://*.example.com:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "https://*.example.com:*" {
+		t.Errorf("Got: %s", p)
+	}
+
+	var cases = map[string]bool{
+		"https://sub.example.com":      true,
+		"https://sub.example.com:8443": true,
+		"https://example.com":          false,
+		"http://sub.example.com":       false,
+		"https://sub.example.dev":      false,
+		"example.com":                  false,
+		"":                             false,
+	}
+
+	for origin, want := range cases {
+		if got := p.Matches(origin); got != want {
+			t.Errorf("Origin: %q - Wanted: %v, Got: %v", origin, want, got)
+		}
+	}
+
+	for _, pattern := range []string{"", "example.com", "https://example.com:abc"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Pattern: %q - expected an error", pattern)
+		}
+	}
+
+	if _, err := Compile("*", AllowCredentials()); err == nil {
+		t.Error("expected an error for a wildcard scheme with credentials")
+	}
+}
+
+func TestCompileExact(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"https://bücher.example", false, true},
+		{"https://xn--bcher-kva.example", false, true},
+		{"https://xn--bcher-kva.example:443", false, true},
+		{"https://xn--bcher-kva.example:0443", false, true},
+		{"HTTPS://XN--BCHER-KVA.EXAMPLE", false, true},
+		{"https://xn--bcher-kva.example:8443", false, false},
+		{"https://sub.xn--bcher-kva.example", false, false},
+		{"http://xn--bcher-kva.example", false, false},
+		{"xn--bcher-kva.example", true, false},
+	}
+
+	p, err := Compile("https://Bücher.example:0443")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		isMatch, err := p.match(tc.Origin)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s - Error: %v", tc.Origin, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s - Wanted: %v, Got: %v", tc.Origin, tc.IsMatch, isMatch)
+		}
+	}
+
+	for _, origin := range []string{"https://xn--bcher-kva.example", "https://example.com"} {
+		if n := testing.AllocsPerRun(100, func() { p.Matches(origin) }); n != 0 {
+			t.Errorf("Origin: %s - Wanted no allocation, Got: %v", origin, n)
+		}
+	}
+}
+
+func TestCompileExactInvalid(t *testing.T) {
+	// Origins equal to a pattern without wildcards must still be valid
+	// to match it, whichever way they are matched.
+	for _, s := range []string{"https://", "https://user@example.com", "https://example.com?x"} {
+		if ok, err := Match(s, s); ok || err == nil {
+			t.Errorf("Match(%q, %q) - Wanted an error, Got: %v, %v", s, s, ok, err)
+		}
+		if ok, err := (Patterns{s}).Match(s); ok || err == nil {
+			t.Errorf("Patterns{%q}.Match(%q) - Wanted an error, Got: %v, %v", s, s, ok, err)
+		}
+		set, err := NewPatternSet(Patterns{s})
+		if err == nil {
+			var ok bool
+			ok, err = set.Match(s)
+			if ok {
+				t.Errorf("NewPatternSet(%q).Match(%q) - Wanted no match", s, s)
+			}
+		}
+		if err == nil {
+			t.Errorf("NewPatternSet(%q).Match(%q) - Wanted an error", s, s)
+		}
+	}
+}
+
+func TestCompileSchemeSet(t *testing.T) {
+	type testCase struct {
+		Origin   string
+		HasError bool
+		IsMatch  bool
+	}
+
+	var cases = []*testCase{
+		{"http://example.com", false, true},
+		{"https://example.com", false, true},
+		{"https://example.com:8443", false, true},
+		{"http://example.com:3000", false, true},
+		{"wss://example.com", false, false},
+		{"https://sub.example.com", false, false},
+		{"example.com", true, false},
+	}
+
+	p, err := Compile("{http,HTTPS}://example.com:*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range cases {
+		isMatch, err := p.match(tc.Origin)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Origin: %s - Error: %v", tc.Origin, err)
+		}
+		if tc.IsMatch != isMatch {
+			t.Errorf("Origin: %s - Wanted: %v, Got: %v", tc.Origin, tc.IsMatch, isMatch)
+		}
+	}
+
+	// Each scheme has its own standard port.
+	p = MustCompile("{http,https}://example.com")
+	if !p.Matches("http://example.com") || !p.Matches("https://example.com:443") || p.Matches("http://example.com:443") {
+		t.Error("Wanted the standard port of each scheme")
+	}
+
+	added, removed := Diff(Patterns{"{https,http}://Example.com:*"}, Patterns{"{http,https,http}://example.com:*"})
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("Diff - Added: %v, Removed: %v", added, removed)
+	}
+
+	for pattern, pos := range map[string]int{
+		"{http,https://example.com": 0,
+		"{http,*}://example.com":    6,
+		"{http,https}://:abc":       16,
+		"{}://example.com":          1,
+	} {
+		_, err := Compile(pattern)
+		if perr, ok := err.(*ErrInvalidPattern); !ok || perr.Pos != pos || perr.Pattern != pattern {
+			t.Errorf("Pattern: %q - Wanted an error at %d, Got: %#v", pattern, pos, err)
+		}
+	}
+}
+
+func TestCompileLoopback(t *testing.T) {
+	var cases = map[string]bool{
+		"http://localhost":           true,
+		"http://localhost:3000":      true,
+		"https://localhost:8443":     true,
+		"http://app.localhost:5173":  true,
+		"http://a.b.localhost":       true,
+		"http://127.0.0.1:8080":      true,
+		"http://127.42.0.1":          true,
+		"http://[::1]:3000":          true,
+		"ws://localhost:3000":        false,
+		"http://128.0.0.1":           false,
+		"http://[::2]":               false,
+		"http://localhost.evil.com":  false,
+		"https://example.com":        false,
+		"http://evil-localhost:3000": false,
+	}
+
+	opts := [][]Option{
+		nil,
+		{DenyPublicSuffixWildcards(), DenyGlobalWildcards(), MaxWildcards(0), AnchorHostnames()},
+		{MatchRegistrableDomain()},
+		{WithWildcard('%'), MaxSubdomainDepth(1)},
+	}
+	for _, opt := range opts {
+		p, err := Compile(Loopback, opt...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for origin, want := range cases {
+			if got := p.Matches(origin); got != want {
+				t.Errorf("Origin: %s - Wanted: %v, Got: %v", origin, want, got)
+			}
+		}
+	}
+
+	if ok, _ := (Patterns{Loopback, "!http://localhost:6379"}).Match("http://localhost:6379"); ok {
+		t.Error("Wanted the negated pattern to take precedence")
+	}
+}
+
+func TestMustCompile(t *testing.T) {
+	if p := MustCompile("%://example.com:%", WithWildcard('%')); !p.Matches("http://example.com:8080") {
+		t.Error("expected a match")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	MustCompile("example.com")
+}
+
+func TestPatternJSON(t *testing.T) {
+	type config struct {
+		Primary *Pattern `json:"primary"`
+		Allowed Patterns `json:"allowed"`
+	}
+
+	var c config
+	data := `{"primary": "https://*.example.com", "allowed": ["https://example.dev", "!https://legacy.example.dev"]}`
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		t.Fatal(err)
+	}
+	if !c.Primary.Matches("https://sub.example.com") || len(c.Allowed) != 2 {
+		t.Errorf("Got: %v, %q", c.Primary, c.Allowed)
+	}
+
+	out, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"primary":"https://*.example.com","allowed":["https://example.dev","!https://legacy.example.dev"]}`; string(out) != want {
+		t.Errorf("Wanted: %s, Got: %s", want, out)
+	}
+
+	for data, want := range map[string]string{
+		`{"primary": "example.com"}`:                                    "example.com",
+		`{"allowed": ["https://example.dev", "https://*.*.*.*.dev:x"]}`: "pattern #1",
+		`{"allowed": "https://example.dev"}`:                            "cannot unmarshal",
+		// Patterns that are only valid with options are rejected.
+		`{"allowed": ["null"]}`:                       "opaque origin not allowed",
+		`{"allowed": ["re:^https://example\\.dev$"]}`: "pattern #0",
+		`{"allowed": ["myapp://example.com"]}`:        "missing port",
+		`{"primary": "null"}`:                         "opaque origin not allowed",
+	} {
+		err := json.Unmarshal([]byte(data), &config{})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Data: %s - Wanted an error mentioning %q, Got: %v", data, want, err)
+		}
+	}
+}
+
+func TestPatternSpecificity(t *testing.T) {
+	// From the most to the least specific.
+	ranked := []string{
+		"https://admin.example.com",
+		"{http,https}://admin.example.com",
+		"*://admin.example.com:443",
+		"https://admin.example.com:443,8443",
+		"https://admin.example.com:*",
+		"https://a.*.example.com",
+		"https://pr-*.example.com",
+		"https://*.example.com",
+		"https://.example.com",
+		"https://*.*",
+		"https://*",
+		"*",
+	}
+
+	for i := 1; i < len(ranked); i++ {
+		a, b := MustCompile(ranked[i-1]), MustCompile(ranked[i])
+		if a.Specificity() <= b.Specificity() {
+			t.Errorf("Wanted %q (%d) more specific than %q (%d)", a, a.Specificity(), b, b.Specificity())
+		}
+	}
+}
+
+func TestCompileRegexp(t *testing.T) {
+	p, err := CompileRegexp(`https://pr-\d+\.preview\.example\.com`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cases = map[string]bool{
+		"https://pr-42.preview.example.com":      true,
+		"https://pr-1.preview.example.com":       true,
+		"https://pr-abc.preview.example.com":     false,
+		"https://pr-42.preview.example.com.evil": false,
+		"https://evil.com/https://pr-42.preview": false,
+		"http://pr-42.preview.example.com":       false,
+		"https://pr-42.preview.example.com:8443": false,
+		"pr-42.preview.example.com":              false,
+	}
+
+	for origin, want := range cases {
+		if got := p.Matches(origin); got != want {
+			t.Errorf("Origin: %q - Wanted: %v, Got: %v", origin, want, got)
+		}
+	}
+
+	if _, err := CompileRegexp(`https://(`); err == nil {
+		t.Error("expected an error")
+	}
+
+	ok, err := MatchWith("https://pr-7.preview.example.com", `re:https://pr-\d+\.preview\.example\.com`, AllowRegexp())
+	if !ok || err != nil {
+		t.Errorf("Got: %v, %v", ok, err)
+	}
+	if _, err := Match("https://pr-7.preview.example.com", `re:https://pr-\d+\.preview\.example\.com`); err == nil {
+		t.Error("expected an error without the AllowRegexp option")
+	}
+}
@@ -0,0 +1,84 @@
+package origin
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Policy associates a list of patterns with the options applied to the
+// requests from the origins they match, such as [AllowCredentials],
+// [ExposedHeaders] or [MaxAge].
+type Policy struct {
+	Patterns Patterns // the origins the policy applies to
+	Options  []Option // the options of the policy
+}
+
+// PolicyMiddleware returns a middleware enforcing a CORS policy on the
+// requests served by the handler it wraps, as [Middleware] does, with
+// the options of the first of the policies whose patterns match the
+// origin of each request. For example, partner origins may be trusted
+// with credentials, while any other origin is trusted without:
+//
+//	origin.PolicyMiddleware([]origin.Policy{
+//		{Patterns: origin.Patterns{"https://partner.example.com"}, Options: []origin.Option{origin.AllowCredentials()}},
+//		{Patterns: origin.Patterns{"*"}},
+//	})
+//
+// The options of each policy apply on top of opts, which are shared by
+// all the policies. Negated patterns only deny origins within their own
+// policy, so that the following ones may still allow them. A policy
+// setting [AllowSameHost] trusts the origin of the requested host, if no
+// previous policy matched it, while [AllowSameHost] in opts only does if
+// none of the policies match it, with the options in opts.
+//
+// The options handling the requests that no policy trusts, namely
+// [PassThrough], [OnReject], [RejectStatus], [OnDeny] and [WithMetrics],
+// only apply in opts, and PolicyMiddleware panics if a policy sets them.
+// It also panics if any of the patterns or options is invalid.
+func PolicyMiddleware(policies []Policy, opts ...Option) func(http.Handler) http.Handler {
+	c, err := newConfig(opts)
+	if err != nil {
+		panic("origin: PolicyMiddleware: " + err.Error())
+	}
+
+	compiled := make([]policy, len(policies))
+	for i, p := range policies {
+		own, err := newConfig(p.Options)
+		if err == nil {
+			err = own.sharedOnly()
+		}
+		var pc *config
+		if err == nil {
+			pc, err = newConfig(append(opts[:len(opts):len(opts)], p.Options...))
+		}
+		if err == nil {
+			compiled[i].compiled, err = compilePatterns(p.Patterns, pc)
+		}
+		if err != nil {
+			panic(fmt.Sprintf("origin: PolicyMiddleware: policy #%d: %v", i, err))
+		}
+		compiled[i].c, compiled[i].sameHost = pc, own.sameHost
+	}
+	return middleware(compiled, c)
+}
+
+// sharedOnly returns an error if c sets any of the options that don't
+// apply to a single policy.
+func (c *config) sharedOnly() error {
+	var name string
+	switch {
+	case c.passThrough:
+		name = "PassThrough"
+	case c.onReject != nil:
+		name = "OnReject"
+	case c.rejectStatus != defaultConfig.rejectStatus:
+		name = "RejectStatus"
+	case c.onDeny != nil:
+		name = "OnDeny"
+	case c.metrics != nil:
+		name = "WithMetrics"
+	default:
+		return nil
+	}
+	return fmt.Errorf("%s only applies to all the policies", name)
+}
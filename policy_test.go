@@ -0,0 +1,122 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPolicyMiddleware(t *testing.T) {
+	type testCase struct {
+		Origin      string
+		Status      int
+		Credentials string
+		Expose      string
+	}
+
+	var cases = []*testCase{
+		{"https://partner.example.com", http.StatusOK, "true", "X-Partner-ID"},
+		{"https://app.example.com", http.StatusOK, "", "X-Request-ID"},
+		{"https://legacy.example.com", http.StatusForbidden, "", ""},
+		{"https://example.dev", http.StatusForbidden, "", ""},
+	}
+
+	h := PolicyMiddleware([]Policy{
+		{
+			Patterns: Patterns{"https://partner.example.com"},
+			Options:  []Option{AllowCredentials(), ExposedHeaders("X-Partner-ID")},
+		},
+		{
+			Patterns: Patterns{"https://*.example.com", "!https://legacy.example.com"},
+		},
+	}, ExposedHeaders("X-Request-ID"))(hello)
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Origin", tc.Origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status {
+			t.Errorf("Origin: %q - Wanted status: %d, Got: %d", tc.Origin, tc.Status, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tc.Credentials {
+			t.Errorf("Origin: %q - Wanted Access-Control-Allow-Credentials: %q, Got: %q", tc.Origin, tc.Credentials, got)
+		}
+		if got := w.Header().Get("Access-Control-Expose-Headers"); got != tc.Expose {
+			t.Errorf("Origin: %q - Wanted Access-Control-Expose-Headers: %q, Got: %q", tc.Origin, tc.Expose, got)
+		}
+	}
+}
+
+func TestPolicyMiddlewareInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	// Wildcard schemes are not allowed with credentials.
+	PolicyMiddleware([]Policy{{Patterns: Patterns{"*"}, Options: []Option{AllowCredentials()}}})
+}
+
+func TestPolicyMiddlewareSameHost(t *testing.T) {
+	type testCase struct {
+		Origin      string
+		Status      int
+		Credentials string
+	}
+
+	var cases = []*testCase{
+		{"https://api.example.com", http.StatusOK, "true"},
+		{"http://api.example.com", http.StatusForbidden, ""},
+		{"https://app.example.com", http.StatusOK, ""},
+		{"https://example.dev", http.StatusForbidden, ""},
+	}
+
+	h := PolicyMiddleware([]Policy{
+		{
+			Patterns: Patterns{"https://partner.example.com"},
+			Options:  []Option{AllowCredentials(), AllowSameHost()},
+		},
+		{
+			Patterns: Patterns{"https://*.example.com"},
+		},
+	})(hello)
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "https://api.example.com/", nil)
+		r.Header.Set("Origin", tc.Origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status {
+			t.Errorf("Origin: %q - Wanted status: %d, Got: %d", tc.Origin, tc.Status, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tc.Credentials {
+			t.Errorf("Origin: %q - Wanted Access-Control-Allow-Credentials: %q, Got: %q", tc.Origin, tc.Credentials, got)
+		}
+	}
+}
+
+func TestPolicyMiddlewareSharedOnly(t *testing.T) {
+	var cases = []Option{
+		PassThrough(),
+		OnReject(http.NotFoundHandler()),
+		RejectStatus(http.StatusNotFound),
+		OnDeny(func(string) {}),
+	}
+
+	for _, opt := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			PolicyMiddleware([]Policy{{Patterns: Patterns{"https://example.com"}, Options: []Option{opt}}})
+		}()
+	}
+
+	// The same options are valid when shared by all the policies.
+	PolicyMiddleware([]Policy{{Patterns: Patterns{"https://example.com"}}}, PassThrough(), RejectStatus(http.StatusNotFound))
+}
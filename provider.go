@@ -0,0 +1,197 @@
+package origin
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Provider is the interface implemented by the sources of patterns that
+// may change over time, such as a database or a configuration service.
+//
+// Patterns returns the current list of patterns, or an error if it
+// can't be retrieved.
+type Provider interface {
+	Patterns(ctx context.Context) (Patterns, error)
+}
+
+// ProviderFunc is an adapter allowing the use of an ordinary function
+// as a [Provider].
+type ProviderFunc func(ctx context.Context) (Patterns, error)
+
+// Patterns implements the [Provider] interface, calling f(ctx).
+func (f ProviderFunc) Patterns(ctx context.Context) (Patterns, error) {
+	return f(ctx)
+}
+
+// FileProvider returns a [Provider] reading the patterns listed in the
+// file at path, validated according to the given options, as
+// [LoadFileWith] does.
+func FileProvider(path string, opts ...Option) Provider {
+	return &fileProvider{path, opts}
+}
+
+// fileProvider is the Provider returned by FileProvider.
+type fileProvider struct {
+	path string
+	opts []Option
+}
+
+// Patterns implements the [Provider] interface.
+func (p *fileProvider) Patterns(context.Context) (Patterns, error) {
+	return LoadFileWith(p.path, p.opts...)
+}
+
+// WatchFile returns a [Refresher] trusting the patterns listed in the
+// file at path, as [LoadFileWith] reads them, compiled according to the
+// given options, and reading the file again periodically, as set with
+// [RefreshEvery], until ctx is done. Changes to the file therefore take
+// effect without restarting the program.
+//
+// When the file can't be read, or is invalid, the patterns read last
+// remain in use, and the error is reported to the function registered
+// with [OnRefreshError], if any.
+func WatchFile(ctx context.Context, path string, opts ...Option) (*Refresher, error) {
+	return NewRefresher(ctx, FileProvider(path, opts...), opts...)
+}
+
+// Refresher is a [Matcher] trusting the patterns returned by a
+// [Provider], which it queries periodically, as set with [RefreshEvery].
+// When the provider returns an error, or invalid patterns, the patterns
+// it returned last remain in use, and the error is reported to the
+// function registered with [OnRefreshError], if any.
+//
+// A Refresher is safe for concurrent use, provided that its provider is.
+type Refresher struct {
+	p     Provider
+	store *Store
+
+	refresh sync.Mutex // serializes refreshes
+
+	mu  sync.Mutex
+	err error // returned by the last refresh
+}
+
+// NewRefresher returns a [Refresher] trusting the patterns returned by
+// p, compiled according to the given options.
+//
+// The patterns are retrieved once before NewRefresher returns, and an
+// error is returned if it fails, since there are no patterns to fall
+// back to yet. They are then retrieved again periodically, until ctx is
+// done.
+func NewRefresher(ctx context.Context, p Provider, opts ...Option) (*Refresher, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	return newRefresher(ctx, p, c)
+}
+
+func newRefresher(ctx context.Context, p Provider, c *config) (*Refresher, error) {
+	r := &Refresher{p: p, store: &Store{c: c}}
+	if err := r.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		timer := time.NewTimer(c.nextRefresh())
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				if err := r.Refresh(ctx); err != nil && ctx.Err() == nil && c.onRefreshError != nil {
+					c.onRefreshError(err)
+				}
+				timer.Reset(c.nextRefresh())
+			}
+		}
+	}()
+	return r, nil
+}
+
+// nextRefresh returns the duration until the next refresh of the
+// patterns of a Refresher.
+func (c *config) nextRefresh() time.Duration {
+	if c.jitter <= 0 {
+		return c.refresh
+	}
+	return c.refresh + time.Duration(rand.Int63n(int64(c.jitter)+1))
+}
+
+// Refresh retrieves the patterns of r from its provider, and replaces
+// the ones in use with them, unless they are identical. If the provider
+// returns an error, or any of the patterns is invalid, an error is
+// returned, and the patterns of r are left unchanged.
+func (r *Refresher) Refresh(ctx context.Context) error {
+	r.refresh.Lock()
+	defer r.refresh.Unlock()
+
+	patterns, err := r.p.Patterns(ctx)
+	if current, _ := r.store.snapshot(); err == nil && !slices.Equal(patterns, current) {
+		err = r.store.Replace(patterns)
+	}
+
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+	return err
+}
+
+// Err returns the error returned by the last attempt to retrieve the
+// patterns of r, or nil if it succeeded.
+func (r *Refresher) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Patterns returns a copy of the patterns in use.
+func (r *Refresher) Patterns() Patterns {
+	return r.store.Patterns()
+}
+
+// Match returns true if origin matches any of the patterns in use, and
+// none of the negated ones. An error is returned if origin is not a
+// valid origin.
+func (r *Refresher) Match(origin string) (bool, error) {
+	return r.store.Match(origin)
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (r *Refresher) MatchOrigin(origin string) (bool, error) {
+	return r.store.Match(origin)
+}
+
+func (r *Refresher) matchPattern(origin string) (string, bool, error) {
+	return r.store.matchPattern(origin)
+}
+
+// version implements the versioned interface.
+func (r *Refresher) version() uint64 {
+	return r.store.version()
+}
+
+// ProviderMiddleware returns a middleware enforcing a CORS policy as
+// [Middleware] does, trusting the origins that match the patterns
+// returned by p, which are refreshed periodically as described in
+// [NewRefresher], until ctx is done.
+//
+// An error is returned if any of the options is invalid, or if the
+// patterns can't be retrieved initially.
+func ProviderMiddleware(ctx context.Context, p Provider, opts ...Option) (func(http.Handler) http.Handler, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := newRefresher(ctx, p, c)
+	if err != nil {
+		return nil, err
+	}
+	return middleware([]policy{{c: c, store: r.store}}, c), nil
+}
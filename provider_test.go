@@ -0,0 +1,153 @@
+package origin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testProvider is a Provider returning the patterns and error it is
+// set with.
+type testProvider struct {
+	mu       sync.Mutex
+	patterns Patterns
+	err      error
+}
+
+func (p *testProvider) Patterns(context.Context) (Patterns, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.patterns, p.err
+}
+
+func (p *testProvider) set(patterns Patterns, err error) {
+	p.mu.Lock()
+	p.patterns, p.err = patterns, err
+	p.mu.Unlock()
+}
+
+func TestRefresher(t *testing.T) {
+	p := &testProvider{patterns: Patterns{"https://example.com"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, err := NewRefresher(ctx, p, RefreshEvery(time.Hour, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(origin string, want bool) {
+		t.Helper()
+		if got, err := r.MatchOrigin(origin); got != want || err != nil {
+			t.Errorf("Origin: %q - Wanted: %v, Got: %v, %v", origin, want, got, err)
+		}
+	}
+	check("https://example.com", true)
+	check("https://example.dev", false)
+
+	p.set(Patterns{"https://example.dev"}, nil)
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check("https://example.com", false)
+	check("https://example.dev", true)
+
+	// Stale patterns are kept when the provider fails, or returns invalid
+	// patterns.
+	p.set(nil, errors.New("unavailable"))
+	if err := r.Refresh(ctx); err == nil || r.Err() == nil {
+		t.Error("expected an error")
+	}
+	p.set(Patterns{"example.org"}, nil)
+	if err := r.Refresh(ctx); err == nil || r.Err() == nil {
+		t.Error("expected an error")
+	}
+	check("https://example.dev", true)
+
+	p.set(Patterns{"https://example.org"}, nil)
+	if err := r.Refresh(ctx); err != nil || r.Err() != nil {
+		t.Fatal(err)
+	}
+	check("https://example.org", true)
+}
+
+func TestRefresherPeriodic(t *testing.T) {
+	p := &testProvider{patterns: Patterns{"https://example.com"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, err := NewRefresher(ctx, p, RefreshEvery(10*time.Millisecond, 5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.set(Patterns{"https://example.dev"}, nil)
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if ok, _ := r.MatchOrigin("https://example.dev"); ok {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("patterns were not refreshed")
+}
+
+func TestNewRefresherError(t *testing.T) {
+	ctx := context.Background()
+
+	failing := ProviderFunc(func(context.Context) (Patterns, error) {
+		return nil, errors.New("unavailable")
+	})
+	if _, err := NewRefresher(ctx, failing); err == nil {
+		t.Error("expected an error for a failing provider")
+	}
+
+	p := &testProvider{patterns: Patterns{"https://example.com"}}
+	for _, opt := range []Option{RefreshEvery(0, 0), RefreshEvery(time.Minute, -time.Second)} {
+		if _, err := NewRefresher(ctx, p, opt); err == nil {
+			t.Error("expected an error for an invalid refresh interval")
+		}
+	}
+}
+
+func TestProviderMiddleware(t *testing.T) {
+	p := &testProvider{patterns: Patterns{"https://example.com"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mw, err := ProviderMiddleware(ctx, p, RefreshEvery(10*time.Millisecond, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := mw(hello)
+
+	status := func(origin string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := status("https://example.com"); code != http.StatusOK {
+		t.Errorf("Wanted status: %d, Got: %d", http.StatusOK, code)
+	}
+	if code := status("https://example.dev"); code != http.StatusForbidden {
+		t.Errorf("Wanted status: %d, Got: %d", http.StatusForbidden, code)
+	}
+
+	p.set(Patterns{"https://example.dev"}, nil)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		if status("https://example.dev") == http.StatusOK {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Error("patterns were not refreshed")
+}
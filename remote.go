@@ -0,0 +1,120 @@
+package origin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// maxRemoteSize is the largest response body accepted by a [Remote].
+const maxRemoteSize = 1 << 20
+
+// Remote is a [Refresher] trusting the patterns listed by a JSON
+// document served over HTTPS, such as an allow-list managed centrally
+// for several services. The document is formatted as expected by
+// [LoadFileEntries] for JSON files.
+//
+// The document is fetched again periodically, as set with
+// [RefreshEvery], with the ETag of the last response, if any, so that
+// the server doesn't need to send it again when it hasn't changed. When
+// it can't be fetched, or is invalid, the patterns fetched last remain
+// in use, and the error is reported to the function registered with
+// [OnRefreshError], if any.
+//
+// A Remote is safe for concurrent use.
+type Remote struct {
+	*Refresher
+}
+
+// NewRemote returns a [Remote] trusting the patterns listed by the
+// document at rawURL, compiled according to the given options, and
+// fetched with client, or [http.DefaultClient] if client is nil.
+//
+// The document is fetched once before NewRemote returns, and an error
+// is returned if it fails, since there are no patterns to fall back to
+// yet. It is then fetched again periodically, until ctx is done.
+func NewRemote(ctx context.Context, client *http.Client, rawURL string, opts ...Option) (*Remote, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("remote patterns must be served over HTTPS: %q", rawURL)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newRefresher(ctx, &remoteProvider{url: rawURL, client: client}, c)
+	if err != nil {
+		return nil, err
+	}
+	return &Remote{r}, nil
+}
+
+// remoteProvider is the Provider fetching the patterns of a Remote.
+type remoteProvider struct {
+	url    string
+	client *http.Client
+
+	mu       sync.Mutex
+	etag     string   // of the last response
+	patterns Patterns // listed by the last response
+}
+
+// Patterns implements the [Provider] interface. The patterns fetched
+// last are returned again if the server reports that the document
+// hasn't changed.
+func (p *remoteProvider) Patterns(ctx context.Context) (Patterns, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		return p.patterns, nil
+	default:
+		return nil, fmt.Errorf("%s: unexpected status: %s", p.url, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxRemoteSize {
+		return nil, fmt.Errorf("%s: document too large", p.url)
+	}
+
+	entries, err := decodeJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.url, err)
+	}
+	patterns := make(Patterns, len(entries))
+	for i, entry := range entries {
+		patterns[i] = entry.Pattern
+	}
+	p.etag, p.patterns = resp.Header.Get("ETag"), patterns
+	return patterns, nil
+}
@@ -0,0 +1,173 @@
+package origin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRemote(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		body     = `["https://example.com"]`
+		status   = http.StatusOK
+		requests int
+		matched  int
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests++
+		etag := `"` + body + `"`
+		if r.Header.Get("If-None-Match") == etag && status == http.StatusOK {
+			matched++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	set := func(b string, s int) {
+		mu.Lock()
+		body, status = b, s
+		mu.Unlock()
+	}
+	check := func(r *Remote, origin string, want bool) {
+		t.Helper()
+		if got, err := r.MatchOrigin(origin); got != want || err != nil {
+			t.Errorf("Origin: %q - Wanted: %v, Got: %v, %v", origin, want, got, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, err := NewRemote(ctx, srv.Client(), srv.URL, RefreshEvery(time.Hour, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(r, "https://example.com", true)
+	check(r, "https://example.dev", false)
+
+	if err := r.Refresh(ctx); err != nil || matched != 1 {
+		t.Errorf("Wanted a conditional request, Got: %d (%v)", matched, err)
+	}
+
+	set(`{"patterns": ["https://example.dev", {"pattern": "https://*.example.dev"}]}`, http.StatusOK)
+	if err := r.Refresh(ctx); err != nil {
+		t.Fatal(err)
+	}
+	check(r, "https://example.com", false)
+	check(r, "https://sub.example.dev", true)
+	if want := (Patterns{"https://example.dev", "https://*.example.dev"}); !reflect.DeepEqual(r.Patterns(), want) {
+		t.Errorf("Wanted: %q, Got: %q", want, r.Patterns())
+	}
+
+	// Stale patterns are kept when the document can't be fetched, or is
+	// invalid.
+	for _, tc := range []struct {
+		Body   string
+		Status int
+	}{
+		{"", http.StatusInternalServerError},
+		{`["example.com"]`, http.StatusOK},
+		{`{`, http.StatusOK},
+	} {
+		set(tc.Body, tc.Status)
+		if err := r.Refresh(ctx); err == nil || r.Err() == nil {
+			t.Errorf("Body: %q, Status: %d - expected an error", tc.Body, tc.Status)
+		}
+		check(r, "https://example.dev", true)
+	}
+
+	set(`["https://example.org"]`, http.StatusOK)
+	if err := r.Refresh(ctx); err != nil || r.Err() != nil {
+		t.Fatal(err)
+	}
+	check(r, "https://example.org", true)
+}
+
+func TestRemotePeriodic(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		body = `["https://example.com"]`
+	)
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	r, err := NewRemote(ctx, srv.Client(), srv.URL, RefreshEvery(10*time.Millisecond, 5*time.Millisecond), OnRefreshError(func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	body = `["https://example.dev"]`
+	mu.Unlock()
+
+	refreshed := false
+	for deadline := time.Now().Add(5 * time.Second); !refreshed && time.Now().Before(deadline); {
+		refreshed, _ = r.MatchOrigin("https://example.dev")
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !refreshed {
+		t.Fatal("patterns were not refreshed")
+	}
+
+	// Errors of periodic refreshes are reported.
+	mu.Lock()
+	body = `["example.dev"]`
+	mu.Unlock()
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expected an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("refresh error was not reported")
+	}
+	if ok, _ := r.MatchOrigin("https://example.dev"); !ok {
+		t.Error("stale patterns were not kept")
+	}
+}
+
+func TestNewRemoteError(t *testing.T) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		URL      string
+		Interval time.Duration
+	}{
+		{srv.URL, time.Minute},
+		{srv.URL, 0},
+		{"http://example.com/origins.json", time.Minute},
+		{"://example.com", time.Minute},
+	} {
+		if _, err := NewRemote(ctx, srv.Client(), tc.URL, RefreshEvery(tc.Interval, 0)); err == nil {
+			t.Errorf("URL: %q, Interval: %v - expected an error", tc.URL, tc.Interval)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package origin
+
+import (
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// SameOrigin returns true if the origin of r, or the origin derived
+// from its referer header when it has none, is the origin r was sent
+// to: same scheme, hostname and port. It is suitable to protect
+// state-changing requests against cross-site request forgery.
+//
+// The scheme r was sent to is HTTPS if the connection uses TLS, and
+// HTTP otherwise. Requests with neither an origin nor a referer header,
+// or with the opaque origin "null", are not considered same-origin.
+func SameOrigin(r *http.Request) bool {
+	return sameOrigin(r, defaultConfig)
+}
+
+// SameOriginWith is like [SameOrigin], with options. With
+// [TrustProxies], the origin r was sent to is derived from the headers
+// set by reverse proxies, as [RequestOrigin] does, when r comes from
+// one of the trusted proxies. SameOriginWith panics if any of the
+// options is invalid.
+func SameOriginWith(r *http.Request, opts ...Option) bool {
+	c, err := newConfig(opts)
+	if err != nil {
+		panic("origin: SameOriginWith: " + err.Error())
+	}
+	return sameOrigin(r, c)
+}
+
+func sameOrigin(r *http.Request, c *config) bool {
+	origin := GetWithRefererFallback(r)
+	if origin == "" || origin == opaque {
+		return false
+	}
+
+	o, err := ParseOrigin(origin)
+	if err != nil {
+		return false
+	}
+	self, err := c.requestOrigin(r)
+	if err != nil {
+		return false
+	}
+	return o.Equal(self)
+}
+
+// RequestOrigin returns the origin r was sent to, such as
+// "https://example.com:8443".
+//
+// The scheme is HTTPS if the connection uses TLS, and HTTP otherwise,
+// and the hostname and port come from the host header. Both are
+// overridden by the Forwarded header (RFC 7239) or, in its absence, by
+// the X-Forwarded-Proto and X-Forwarded-Host headers, as set by reverse
+// proxies. Only the last element of these headers is used, since the
+// proxy closest to the server appends its own to the ones sent by the
+// client. Since clients can set these headers too, they must only be
+// trusted when r comes from a proxy (see [TrustProxies]).
+func RequestOrigin(r *http.Request) (Origin, error) {
+	proto, host := forwardedOrigin(r)
+	if proto == "" {
+		proto = connScheme(r)
+	}
+	if host == "" {
+		host = r.Host
+	}
+	return ParseOrigin(strings.ToLower(proto) + "://" + host)
+}
+
+// requestOrigin returns the origin r was sent to, as RequestOrigin
+// does if r comes from one of the proxies trusted by c, and according
+// to the connection it was received on and its host header otherwise.
+func (c *config) requestOrigin(r *http.Request) (Origin, error) {
+	if c.trustsProxy(r) {
+		return RequestOrigin(r)
+	}
+	return ParseOrigin(connScheme(r) + "://" + r.Host)
+}
+
+// requestHost returns the host r was sent to, including its port if
+// any, honoring the headers set by reverse proxies if r comes from one
+// of the proxies trusted by c.
+func (c *config) requestHost(r *http.Request) string {
+	if c.trustsProxy(r) {
+		if _, host := forwardedOrigin(r); host != "" {
+			return host
+		}
+	}
+	return r.Host
+}
+
+// trustsProxy returns true if r was received from one of the proxies
+// trusted by c.
+func (c *config) trustsProxy(r *http.Request) bool {
+	if len(c.proxies) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range c.proxies {
+		if prefix.Contains(addr.Addr().Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedOrigin returns the protocol and host set by the closest
+// reverse proxy in the Forwarded header of r or, in its absence, in the
+// X-Forwarded-Proto and X-Forwarded-Host headers.
+func forwardedOrigin(r *http.Request) (proto, host string) {
+	if len(r.Header.Values("Forwarded")) > 0 {
+		return forwarded(r)
+	}
+	return lastValue(r.Header.Values("X-Forwarded-Proto")), lastValue(r.Header.Values("X-Forwarded-Host"))
+}
+
+// connScheme returns the scheme of the connection r was received on.
+func connScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// forwarded returns the protocol and host set by the closest proxy in
+// the Forwarded header of r, if any. Earlier elements of the header,
+// which the client may have sent, are ignored.
+func forwarded(r *http.Request) (proto, host string) {
+	element := lastValue(r.Header.Values("Forwarded"))
+	if element == "" {
+		return "", ""
+	}
+
+	for _, pair := range strings.Split(element, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		value = strings.Trim(value, `"`)
+		switch strings.ToLower(key) {
+		case "proto":
+			proto = value
+		case "host":
+			host = value
+		}
+	}
+	return proto, host
+}
+
+// lastValue returns the last of the comma-separated values in the
+// lines of a header, ignoring the commas within quoted strings.
+func lastValue(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+
+	s := lines[len(lines)-1]
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				start = i + 1
+			}
+		}
+	}
+	return strings.TrimSpace(s[start:])
+}
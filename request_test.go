@@ -0,0 +1,183 @@
+package origin
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSameOrigin(t *testing.T) {
+	type testCase struct {
+		Host    string
+		TLS     bool
+		Origin  string
+		Referer string
+		IsSame  bool
+	}
+
+	var cases = []*testCase{
+		{"example.com", true, "https://example.com", "", true},
+		{"example.com:443", true, "https://Example.com", "", true},
+		{"example.com", false, "http://example.com:80", "", true},
+		{"example.com:8080", false, "http://example.com:8080", "", true},
+		{"[::1]:3000", false, "http://[::1]:3000", "", true},
+		{"example.com", true, "", "https://example.com/form", true},
+		{"example.com", true, "http://example.com", "", false},
+		{"example.com", true, "https://sub.example.com", "", false},
+		{"example.com", true, "https://example.com:8443", "", false},
+		{"example.com", true, "https://attacker.example", "https://example.com/form", false},
+		{"example.com", true, "null", "", false},
+		{"example.com", true, "", "", false},
+		{"", true, "https://example.com", "", false},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.Host = tc.Host
+		if !tc.TLS {
+			r.TLS = nil
+		} else if r.TLS == nil {
+			r.TLS = &tls.ConnectionState{}
+		}
+		if tc.Origin != "" {
+			r.Header.Set("Origin", tc.Origin)
+		}
+		if tc.Referer != "" {
+			r.Header.Set("Referer", tc.Referer)
+		}
+
+		if got := SameOrigin(r); got != tc.IsSame {
+			t.Errorf("Host: %q, Origin: %q, Referer: %q - Wanted: %v, Got: %v", tc.Host, tc.Origin, tc.Referer, tc.IsSame, got)
+		}
+	}
+}
+
+func TestRequestOrigin(t *testing.T) {
+	type testCase struct {
+		Host    string
+		TLS     bool
+		Headers map[string]string
+		Want    string
+	}
+
+	var cases = []*testCase{
+		{"example.com", false, nil, "http://example.com"},
+		{"example.com:8443", true, nil, "https://example.com:8443"},
+		{"internal:8080", false, map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "example.com"}, "https://example.com"},
+		{"example.com", false, map[string]string{"X-Forwarded-Proto": "http, HTTPS"}, "https://example.com"},
+		{"internal:8080", false, map[string]string{"X-Forwarded-Host": "evil.com, example.com"}, "http://example.com"},
+		{"internal:8080", false, map[string]string{"Forwarded": `host=evil.com;proto=https, for=192.0.2.60;proto=https;host="example.com:8443"`}, "https://example.com:8443"},
+		{"internal:8080", false, map[string]string{"Forwarded": `for="evil, host=evil.com";proto=https;host=example.com`}, "https://example.com"},
+		{"internal:8080", false, map[string]string{"Forwarded": "host=evil.com;proto=https, for=10.0.0.1"}, "http://internal:8080"},
+		{"example.com", false, map[string]string{"Forwarded": "host=example.dev", "X-Forwarded-Proto": "https"}, "http://example.dev"},
+	}
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = tc.Host
+		if tc.TLS {
+			r.TLS = &tls.ConnectionState{}
+		}
+		for name, value := range tc.Headers {
+			r.Header.Set(name, value)
+		}
+
+		o, err := RequestOrigin(r)
+		if err != nil {
+			t.Errorf("Host: %q - Error: %v", tc.Host, err)
+			continue
+		}
+		if got := o.String(); got != tc.Want {
+			t.Errorf("Host: %q, Headers: %v - Wanted: %q, Got: %q", tc.Host, tc.Headers, tc.Want, got)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = ""
+	if _, err := RequestOrigin(r); err == nil {
+		t.Error("expected an error for a missing host")
+	}
+}
+
+func TestTrustProxies(t *testing.T) {
+	type testCase struct {
+		RemoteAddr string
+		Origin     string
+		IsSame     bool
+	}
+
+	var cases = []*testCase{
+		{"10.0.0.7:51234", "https://example.com", true},
+		{"[::ffff:10.0.0.7]:51234", "https://example.com", true},
+		{"192.168.1.1:51234", "https://example.com", false},
+		{"192.168.1.1:51234", "http://internal:8080", true},
+		{"10.0.0.7:51234", "http://internal:8080", false},
+	}
+
+	opts := []Option{TrustProxies("10.0.0.0/8", "fd00::1")}
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		r.RemoteAddr = tc.RemoteAddr
+		r.Host = "internal:8080"
+		r.Header.Set("X-Forwarded-Proto", "https")
+		r.Header.Set("X-Forwarded-Host", "example.com")
+		r.Header.Set("Origin", tc.Origin)
+
+		if got := SameOriginWith(r, opts...); got != tc.IsSame {
+			t.Errorf("Remote: %s, Origin: %s - Wanted: %v, Got: %v", tc.RemoteAddr, tc.Origin, tc.IsSame, got)
+		}
+	}
+
+	// The same host is derived from the headers of trusted proxies too.
+	h := Middleware(nil, AllowSameHost(), TrustProxies("10.0.0.0/8"))(hello)
+	for addr, want := range map[string]int{"10.0.0.7:51234": http.StatusOK, "192.168.1.1:51234": http.StatusForbidden} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = addr
+		r.Host = "internal:8080"
+		r.Header.Set("X-Forwarded-Host", "example.com")
+		r.Header.Set("Origin", "https://example.com")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != want {
+			t.Errorf("Remote: %s - Wanted status: %d, Got: %d", addr, want, w.Code)
+		}
+	}
+
+	// Only the elements appended by the trusted proxy are honored, not
+	// the ones sent by the client, whether on the same line or not.
+	for _, headers := range []http.Header{
+		{"Forwarded": {"host=evil.com;proto=https, for=192.0.2.60;host=example.com;proto=https"}},
+		{"Forwarded": {"host=evil.com;proto=https", "for=192.0.2.60;host=example.com;proto=https"}},
+		{"Forwarded": {"host=evil.com;proto=https", "for=192.0.2.60"}},
+		{"X-Forwarded-Host": {"evil.com, example.com"}, "X-Forwarded-Proto": {"https, https"}},
+		{"X-Forwarded-Host": {"evil.com", "example.com"}, "X-Forwarded-Proto": {"https", "https"}},
+		{"X-Forwarded-Host": {"evil.com, example.com", "internal:8080"}},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "10.0.0.7:51234"
+		r.Host = "internal:8080"
+		for name, values := range headers {
+			r.Header[name] = values
+		}
+		r.Header.Set("Origin", "https://evil.com")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Headers: %v - Wanted status: %d, Got: %d", headers, http.StatusForbidden, w.Code)
+		}
+	}
+
+	if _, err := newConfig([]Option{TrustProxies("10.0.0.0/33")}); err == nil {
+		t.Error("Wanted an error for an invalid network")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an invalid option")
+		}
+	}()
+	SameOriginWith(httptest.NewRequest(http.MethodGet, "/", nil), TrustProxies("10.0.0.0/33"))
+}
@@ -0,0 +1,85 @@
+package origin
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// RouteMiddleware returns a middleware enforcing a different CORS policy
+// per route, as [Middleware] does, trusting the origins that match the
+// patterns associated with the longest prefix of the request path among
+// the keys of routes. For example, "/public/" may trust any origin,
+// while "/admin/" only trusts the origin of an administration console.
+//
+// The empty prefix "" matches every path, and sets the policy of the
+// requests matching no other prefix. Without it, these requests are
+// rejected as if no origin was trusted, unless they have no origin
+// header.
+//
+// Paths are matched once cleaned, as [http.ServeMux] does before
+// routing requests, so that a path such as "/public/../admin/users"
+// gets the policy of "/admin/".
+//
+// The options apply to every route. RouteMiddleware panics if any of
+// the patterns or options is invalid.
+func RouteMiddleware(routes map[string]Patterns, opts ...Option) func(http.Handler) http.Handler {
+	c, err := newConfig(opts)
+	if err != nil {
+		panic("origin: RouteMiddleware: " + err.Error())
+	}
+
+	prefixes := make([]string, 0, len(routes))
+	for prefix, patterns := range routes {
+		if _, err := compilePatterns(patterns, c); err != nil {
+			panic(fmt.Sprintf("origin: RouteMiddleware: route %q: %v", prefix, err))
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	// Longest prefixes first, so that the first match is the longest.
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	middlewares := make([]func(http.Handler) http.Handler, len(prefixes))
+	for i, prefix := range prefixes {
+		middlewares[i] = Middleware(routes[prefix], opts...)
+	}
+	fallback := Middleware(nil, opts...)
+
+	return func(next http.Handler) http.Handler {
+		handlers := make([]http.Handler, len(middlewares))
+		for i, m := range middlewares {
+			handlers[i] = m(next)
+		}
+		deny := fallback(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p := cleanPath(r.URL.Path)
+			for i, prefix := range prefixes {
+				if strings.HasPrefix(p, prefix) {
+					handlers[i].ServeHTTP(w, r)
+					return
+				}
+			}
+			deny.ServeHTTP(w, r)
+		})
+	}
+}
+
+// cleanPath returns the canonical form of the request path p, as
+// http.ServeMux computes it: rooted, without "." or ".." elements nor
+// repeated slashes, and keeping its trailing slash.
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	if p[0] != '/' {
+		p = "/" + p
+	}
+	np := path.Clean(p)
+	if p[len(p)-1] == '/' && np != "/" {
+		np += "/"
+	}
+	return np
+}
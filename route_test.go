@@ -0,0 +1,64 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteMiddleware(t *testing.T) {
+	type testCase struct {
+		Path   string
+		Origin string
+		Status int
+	}
+
+	var cases = []*testCase{
+		{"/public/file", "https://anything.dev", http.StatusOK},
+		{"/public/file", "https://console.example.com", http.StatusOK},
+		{"/admin/users", "https://console.example.com", http.StatusOK},
+		{"/admin/users", "https://anything.dev", http.StatusForbidden},
+		{"/admin/public/file", "https://anything.dev", http.StatusForbidden},
+		{"/api/items", "https://app.example.com", http.StatusOK},
+		{"/api/items", "https://console.example.com", http.StatusForbidden},
+		{"/", "https://app.example.com", http.StatusOK},
+		{"/admin/users", "", http.StatusOK},
+		{"/public/../admin/users", "https://anything.dev", http.StatusForbidden},
+		{"/public//..//admin/users", "https://anything.dev", http.StatusForbidden},
+		{"/public/./file", "https://anything.dev", http.StatusOK},
+		{"/admin/../public/", "https://anything.dev", http.StatusOK},
+	}
+
+	routes := map[string]Patterns{
+		"/public/": {"*"},
+		"/admin/":  {"https://console.example.com"},
+		"":         {"https://app.example.com"},
+	}
+	h := RouteMiddleware(routes)(hello)
+
+	for _, tc := range cases {
+		r := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+		if tc.Origin != "" {
+			r.Header.Set("Origin", tc.Origin)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Code != tc.Status {
+			t.Errorf("Path: %s, Origin: %q - Wanted status: %d, Got: %d", tc.Path, tc.Origin, tc.Status, w.Code)
+		}
+	}
+
+	// Without a default route, cross-origin requests to other paths are
+	// rejected.
+	delete(routes, "")
+	h = RouteMiddleware(routes)(hello)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Wanted status: %d, Got: %d", http.StatusForbidden, w.Code)
+	}
+}
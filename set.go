@@ -0,0 +1,215 @@
+package origin
+
+import (
+	"net/netip"
+	"sort"
+	"strings"
+)
+
+// PatternSet is a set of compiled patterns, indexing their hostnames
+// in a trie of labels, from right to left. Matching an origin against
+// a PatternSet takes time proportional to the number of labels in its
+// hostname, rather than to the number of patterns, which suits lists
+// of thousands of patterns.
+//
+// Patterns whose hostname is a wildcard, an IP address or network, or
+// a regular expression are not indexed, and are evaluated in order.
+//
+// A PatternSet is safe for concurrent use.
+type PatternSet struct {
+	root   setNode
+	others []setEntry // patterns whose hostname is not indexed
+	denies int        // number of negated patterns
+	len    int
+	c      *config
+}
+
+// setEntry is a compiled pattern, along with its position in the list
+// the set was built from.
+type setEntry struct {
+	p     *Pattern
+	index int
+}
+
+// setNode is a node of the hostname trie of a [PatternSet].
+type setNode struct {
+	children map[string]*setNode
+	exact    []setEntry // patterns whose hostname ends at this node
+	suffix   []setEntry // patterns also matching the subdomains of this node
+}
+
+// NewPatternSet compiles each of the patterns according to the given
+// options, and returns a set holding them.
+//
+// The set matches the same origins as the list of patterns, negated
+// patterns included.
+func NewPatternSet(patterns Patterns, opts ...Option) (*PatternSet, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled, err := compilePatterns(patterns, c)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PatternSet{len: len(compiled), c: c}
+	for i, p := range compiled {
+		s.insert(setEntry{p, i})
+	}
+	return s, nil
+}
+
+// insert adds e to the set.
+func (s *PatternSet) insert(e setEntry) {
+	if e.p.deny {
+		s.denies++
+	}
+
+	h := &e.p.host
+	if e.p.re != nil || h.labels == nil {
+		s.others = append(s.others, e)
+		return
+	}
+
+	n := &s.root
+	for i := len(h.labels) - 1; i >= 0; i-- {
+		label := h.labels[i]
+		if s.c.hasWildcard(label) {
+			// Labels such as "pr-*" are indexed as whole-label wildcards,
+			// and only matched once candidates are evaluated.
+			label = s.c.wildcard
+		} else {
+			label = s.c.unescape(label)
+		}
+
+		next := n.children[label]
+		if next == nil {
+			if n.children == nil {
+				n.children = make(map[string]*setNode)
+			}
+			next = &setNode{}
+			n.children[label] = next
+		}
+		n = next
+	}
+
+	if h.suffix || h.deep {
+		n.suffix = append(n.suffix, e)
+	} else {
+		n.exact = append(n.exact, e)
+	}
+}
+
+// Len returns the number of patterns in s.
+func (s *PatternSet) Len() int {
+	return s.len
+}
+
+// Match returns true if origin matches any of the patterns in s, and
+// none of its negated patterns. An error is returned if origin is not
+// a valid origin.
+func (s *PatternSet) Match(origin string) (bool, error) {
+	_, ok, err := s.matchPattern(origin)
+	return ok, err
+}
+
+// MatchPattern returns the pattern matching origin that comes first in
+// the list s was built from, or an empty string if there is none.
+func (s *PatternSet) MatchPattern(origin string) (string, error) {
+	pattern, _, err := s.matchPattern(origin)
+	return pattern, err
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (s *PatternSet) MatchOrigin(origin string) (bool, error) {
+	return s.Match(origin)
+}
+
+// matchPattern returns the pattern matching origin that comes first in
+// the list the set was built from, like [Patterns.MatchIndex] does.
+func (s *PatternSet) matchPattern(origin string) (string, bool, error) {
+	pattern, ok, err := s.lookup(origin)
+	s.c.report(nil, origin, pattern, ok && err == nil, err)
+	return pattern, ok, err
+}
+
+// lookup is like matchPattern, without reporting the decision.
+func (s *PatternSet) lookup(origin string) (string, bool, error) {
+	if origin == "" {
+		return "", false, nil
+	}
+
+	var os, oh, op string
+	candidates := append([]setEntry(nil), s.others...)
+	if origin != opaque {
+		var err error
+		if os, oh, op, _, err = split(origin, s.c); err != nil {
+			return "", false, err
+		}
+
+		host := normalizeHost(oh)
+		if _, err := netip.ParseAddr(host); err != nil && strings.Count(host, ".") < s.c.maxLabels {
+			candidates = s.root.lookup(candidates, strings.Split(host, "."), s.c)
+		}
+	}
+	if s.denies == 0 {
+		// Candidates only need to be evaluated in order.
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].index < candidates[j].index })
+	}
+
+	var first *setEntry
+	for i, e := range candidates {
+		if first != nil && s.denies == 0 {
+			break
+		}
+		if !e.p.deny && first != nil && first.index < e.index {
+			continue
+		}
+
+		ok, err := e.p.matchSplit(origin, os, oh, op)
+		if err != nil {
+			return "", false, err
+		}
+		switch {
+		case !ok:
+		case e.p.deny:
+			return "", false, nil
+		default:
+			first = &candidates[i]
+		}
+	}
+
+	if first == nil {
+		return "", false, nil
+	}
+	return first.p.raw, true, nil
+}
+
+// lookup appends to candidates the patterns whose hostname may match
+// the given labels, below n.
+func (n *setNode) lookup(candidates []setEntry, labels []string, c *config) []setEntry {
+	candidates = append(candidates, n.suffix...)
+	if len(labels) == 0 {
+		return append(candidates, n.exact...)
+	}
+
+	label := labels[len(labels)-1]
+	rest := labels[:len(labels)-1]
+	if c.originWildcards && label == c.wildcard {
+		// A wildcard in the origin matches any label.
+		for _, child := range n.children {
+			candidates = child.lookup(candidates, rest, c)
+		}
+		return candidates
+	}
+
+	if child := n.children[label]; child != nil {
+		candidates = child.lookup(candidates, rest, c)
+	}
+	if child := n.children[c.wildcard]; child != nil {
+		candidates = child.lookup(candidates, rest, c)
+	}
+	return candidates
+}
@@ -0,0 +1,89 @@
+package origin
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPatternSet(t *testing.T) {
+	var patterns = Patterns{
+		"https://example.com",
+		"https://*.example.com",
+		"!https://legacy.example.com",
+		"https://.example.dev:*",
+		"https://*.*.example.net:8080,8443",
+		"http://localhost:3000-3999",
+		"http://192.168.1.0/24:3000",
+		"*://[::1]:*",
+		"wss://*:443",
+		"https://pr-*.preview.example.org",
+		"https://**.deep.example.org",
+	}
+
+	var origins = []string{
+		"",
+		"https://example.com",
+		"https://sub.example.com",
+		"https://a.sub.example.com",
+		"https://legacy.example.com",
+		"https://example.dev:8443",
+		"https://a.b.example.dev",
+		"https://a.b.example.net:8443",
+		"https://b.example.net:8443",
+		"http://localhost:3000",
+		"http://localhost:4000",
+		"http://192.168.1.42:3000",
+		"http://[::1]:8080",
+		"wss://anything.example.org",
+		"https://example.com.attacker.net",
+		"https://pr-42.preview.example.org",
+		"https://staging.preview.example.org",
+		"https://deep.example.org",
+		"https://a.b.deep.example.org",
+		"example.com",
+	}
+
+	s, err := NewPatternSet(patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != len(patterns) {
+		t.Errorf("Len - Wanted: %d, Got: %d", len(patterns), s.Len())
+	}
+
+	for _, origin := range origins {
+		want, wantErr := patterns.Match(origin)
+		got, err := s.Match(origin)
+		if got != want || (err != nil) != (wantErr != nil) {
+			t.Errorf("Origin: %q - Wanted: %v, %v, Got: %v, %v", origin, want, wantErr, got, err)
+		}
+
+		i, _ := patterns.MatchIndex(origin)
+		if pattern, ok, _ := s.matchPattern(origin); ok && pattern != patterns[i] {
+			t.Errorf("Origin: %q - Wanted pattern: %q, Got: %q", origin, patterns[i], pattern)
+		}
+	}
+
+	if _, err := NewPatternSet(Patterns{"https://example.com", "example.com"}); err == nil {
+		t.Error("Wanted an error for an invalid pattern")
+	}
+}
+
+func TestPatternSetLarge(t *testing.T) {
+	var patterns Patterns
+	for i := 0; i < 10000; i++ {
+		patterns = append(patterns, fmt.Sprintf("https://*.tenant%d.example.com", i))
+	}
+
+	s, err := NewPatternSet(patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pattern, ok, err := s.matchPattern("https://app.tenant9999.example.com"); !ok || err != nil || pattern != patterns[9999] {
+		t.Errorf("Got: %q, %v, %v", pattern, ok, err)
+	}
+	if ok, err := s.Match("https://tenant9999.example.com"); ok || err != nil {
+		t.Errorf("Got: %v, %v", ok, err)
+	}
+}
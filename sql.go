@@ -0,0 +1,55 @@
+package origin
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// Scan implements the [database/sql.Scanner] interface, decoding the
+// patterns stored in a database column, either as a JSON array, or as a
+// list parsed by [ParseList], such as "https://a.com, https://*.b.com".
+// A NULL value is decoded as an empty list.
+//
+// An error is returned if any of the patterns is invalid, in which case
+// p is left unchanged.
+func (p *Patterns) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("cannot scan %T into Patterns", src)
+	}
+
+	if data = bytes.TrimSpace(data); bytes.HasPrefix(data, []byte("[")) {
+		return p.UnmarshalJSON(data)
+	}
+
+	list, err := ParseList(string(data))
+	if err != nil {
+		return err
+	}
+	*p = list
+	return nil
+}
+
+// Value implements the [database/sql/driver.Valuer] interface, encoding
+// the patterns in p as a JSON array, which [Patterns.Scan] decodes. A
+// nil list is stored as NULL.
+func (p Patterns) Value() (driver.Value, error) {
+	if p == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal([]string(p))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,56 @@
+package origin
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPatternsScan(t *testing.T) {
+	type testCase struct {
+		Src      any
+		Patterns Patterns
+		HasError bool
+	}
+
+	var cases = []*testCase{
+		{nil, nil, false},
+		{"", nil, false},
+		{`["https://a.com", "https://*.b.com"]`, Patterns{"https://a.com", "https://*.b.com"}, false},
+		{[]byte(" [\"https://a.com\"]\n"), Patterns{"https://a.com"}, false},
+		{"https://a.com, https://*.b.com", Patterns{"https://a.com", "https://*.b.com"}, false},
+		{[]byte("https://a.com"), Patterns{"https://a.com"}, false},
+		{"{http,https}://a.com:8080,8443", Patterns{"{http,https}://a.com:8080,8443"}, false},
+		{`["https://a.com", "b.com"]`, Patterns{"https://example.com"}, true},
+		{"https://a.com, b.com", Patterns{"https://example.com"}, true},
+		{`["https://a.com"`, Patterns{"https://example.com"}, true},
+		{42, Patterns{"https://example.com"}, true},
+	}
+
+	for _, tc := range cases {
+		p := Patterns{"https://example.com"}
+		err := p.Scan(tc.Src)
+		if hasErr := (err != nil); hasErr != tc.HasError {
+			t.Errorf("Src: %v - Error: %v", tc.Src, err)
+		}
+		if !reflect.DeepEqual(p, tc.Patterns) {
+			t.Errorf("Src: %v - Wanted: %q, Got: %q", tc.Src, tc.Patterns, p)
+		}
+	}
+}
+
+func TestPatternsValue(t *testing.T) {
+	if v, err := Patterns(nil).Value(); v != nil || err != nil {
+		t.Errorf("Wanted NULL, Got: %v, %v", v, err)
+	}
+
+	want := Patterns{"https://a.com", "!https://*.b.com"}
+	v, err := want.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p Patterns
+	if err := p.Scan(v); err != nil || !reflect.DeepEqual(p, want) {
+		t.Errorf("Wanted: %q, Got: %q (%v)", want, p, err)
+	}
+}
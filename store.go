@@ -0,0 +1,148 @@
+package origin
+
+import "sync"
+
+// Store is a list of patterns that can be modified while it is used to
+// match origins, such as an allow-list updated at runtime while request
+// handlers check origins against it.
+//
+// A Store is safe for concurrent use.
+type Store struct {
+	c *config
+
+	mu       sync.RWMutex
+	patterns Patterns
+	compiled []*Pattern
+	ver      uint64 // incremented whenever the patterns change
+}
+
+// NewStore returns a [Store] holding the given patterns, compiled
+// according to the given options.
+func NewStore(patterns Patterns, opts ...Option) (*Store, error) {
+	c, err := newConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{c: c}
+	if err := s.Replace(patterns); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add appends the given patterns to the ones in s. If any of them is
+// invalid, an error is returned and s is left unchanged.
+func (s *Store) Add(patterns ...string) error {
+	compiled, err := compilePatterns(patterns, s.c)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Slices are never modified in place, since concurrent calls to
+	// Match may still be reading them.
+	s.patterns = append(s.patterns[:len(s.patterns):len(s.patterns)], patterns...)
+	s.compiled = append(s.compiled[:len(s.compiled):len(s.compiled)], compiled...)
+	s.ver++
+	return nil
+}
+
+// Remove removes every occurrence of the given patterns from s, and
+// returns the number of patterns removed.
+//
+// Patterns are compared as written, so "https://Example.com" doesn't
+// remove "https://example.com".
+func (s *Store) Remove(patterns ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		kept     Patterns
+		compiled []*Pattern
+	)
+	for i, item := range s.patterns {
+		if !contains(patterns, item) {
+			kept = append(kept, item)
+			compiled = append(compiled, s.compiled[i])
+		}
+	}
+
+	removed := len(s.patterns) - len(kept)
+	if removed > 0 {
+		s.patterns, s.compiled = kept, compiled
+		s.ver++
+	}
+	return removed
+}
+
+// Replace replaces all the patterns in s with the given ones. If any
+// of them is invalid, an error is returned and s is left unchanged.
+func (s *Store) Replace(patterns Patterns) error {
+	compiled, err := compilePatterns(patterns, s.c)
+	if err != nil {
+		return err
+	}
+	patterns = append(Patterns(nil), patterns...)
+
+	s.mu.Lock()
+	s.patterns, s.compiled = patterns, compiled
+	s.ver++
+	s.mu.Unlock()
+	return nil
+}
+
+// snapshot returns the patterns in s, and their compiled form, which
+// must not be modified.
+func (s *Store) snapshot() (Patterns, []*Pattern) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.patterns, s.compiled
+}
+
+// version implements the versioned interface.
+func (s *Store) version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ver
+}
+
+// Patterns returns a copy of the patterns in s.
+func (s *Store) Patterns() Patterns {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append(Patterns(nil), s.patterns...)
+}
+
+// Match returns true if origin matches any of the patterns in s, and
+// none of its negated patterns. An error is returned if origin is not
+// a valid origin.
+func (s *Store) Match(origin string) (bool, error) {
+	_, ok, err := s.matchPattern(origin)
+	return ok, err
+}
+
+// MatchPattern returns the first pattern in s that matches origin, or
+// an empty string if there is none.
+func (s *Store) MatchPattern(origin string) (string, error) {
+	pattern, _, err := s.matchPattern(origin)
+	return pattern, err
+}
+
+// MatchOrigin implements the [Matcher] interface.
+func (s *Store) MatchOrigin(origin string) (bool, error) {
+	return s.Match(origin)
+}
+
+func (s *Store) matchPattern(origin string) (string, bool, error) {
+	patterns, compiled := s.snapshot()
+	i, err := matchFirst(compiled, origin)
+	if i < 0 || err != nil {
+		s.c.report(nil, origin, "", false, err)
+		return "", false, err
+	}
+	s.c.report(nil, origin, patterns[i], true, nil)
+	return patterns[i], true, nil
+}
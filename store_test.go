@@ -0,0 +1,79 @@
+package origin
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	s, err := NewStore(Patterns{"https://example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(origin string, want bool) {
+		t.Helper()
+		if got, err := s.Match(origin); got != want || err != nil {
+			t.Errorf("Origin: %q - Wanted: %v, Got: %v, %v", origin, want, got, err)
+		}
+	}
+
+	check("https://example.com", true)
+	check("https://sub.example.com", false)
+
+	if err := s.Add("https://*.example.com", "!https://legacy.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	check("https://sub.example.com", true)
+	check("https://legacy.example.com", false)
+
+	if err := s.Add("https://example.dev", "example.dev"); err == nil {
+		t.Error("Wanted an error for an invalid pattern")
+	}
+	check("https://example.dev", false)
+
+	if n := s.Remove("!https://legacy.example.com", "https://example.org"); n != 1 {
+		t.Errorf("Wanted 1 pattern removed, Got: %d", n)
+	}
+	check("https://legacy.example.com", true)
+
+	if err := s.Replace(Patterns{"https://example.dev"}); err != nil {
+		t.Fatal(err)
+	}
+	check("https://example.com", false)
+	check("https://example.dev", true)
+
+	if got, want := s.Patterns(), (Patterns{"https://example.dev"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Wanted: %v, Got: %v", want, got)
+	}
+	if _, err := s.Match("example.dev"); err == nil {
+		t.Error("Wanted an error for an invalid origin")
+	}
+}
+
+func TestStoreConcurrent(t *testing.T) {
+	s, err := NewStore(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Add("https://example.com")
+				s.Remove("https://example.com")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Match("https://example.com")
+			}
+		}()
+	}
+	wg.Wait()
+}
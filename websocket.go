@@ -0,0 +1,37 @@
+package origin
+
+import "net/http"
+
+// CheckOrigin returns true if the origin header of r is a valid origin
+// matching p, or if r has no origin header. Its signature is the one of
+// the CheckOrigin field of the Upgrader of [gorilla/websocket], so that
+// it can be used to protect WebSocket servers from cross-site
+// WebSocket hijacking:
+//
+//	upgrader := websocket.Upgrader{CheckOrigin: patterns.CheckOrigin}
+//
+// Browsers always send an origin header with WebSocket handshakes, so
+// that requests without one come from other clients, which any origin
+// check can't protect against.
+//
+// [gorilla/websocket]: https://pkg.go.dev/github.com/gorilla/websocket
+func (p Patterns) CheckOrigin(r *http.Request) bool {
+	return checkOrigin(r, p)
+}
+
+// CheckOrigin returns a function reporting whether the origin header
+// of a request is trusted by m, as [Patterns.CheckOrigin] does.
+func CheckOrigin(m Matcher) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		return checkOrigin(r, m)
+	}
+}
+
+func checkOrigin(r *http.Request, m Matcher) bool {
+	origin := Get(r)
+	if origin == "" {
+		return true
+	}
+	ok, err := m.MatchOrigin(origin)
+	return ok && err == nil
+}
@@ -0,0 +1,37 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	var cases = map[string]bool{
+		"":                        true,
+		"https://example.com":     true,
+		"https://sub.example.com": true,
+		"https://example.dev":     false,
+		"null":                    false,
+		"example.com":             false,
+	}
+
+	p := Patterns{"https://example.com", "https://*.example.com"}
+	s, err := NewPatternSet(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for origin, want := range cases {
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if got := p.CheckOrigin(r); got != want {
+			t.Errorf("Origin: %q - Wanted: %v, Got: %v", origin, want, got)
+		}
+		if got := CheckOrigin(s)(r); got != want {
+			t.Errorf("Origin: %q - Wanted from set: %v, Got: %v", origin, want, got)
+		}
+	}
+}